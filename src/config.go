@@ -1,15 +1,128 @@
 package main
 
+import (
+	"time"
+)
+
 // Config encapsulates the configuration of the service.
 type Config struct {
 
+	// BookingsDBDriver selects the database layer used by the
+	// service. Supported drivers are "mongodb" and "memory". The
+	// "memory" driver keeps all data in memory and is meant for
+	// tests and local development.
+	BookingsDBDriver string `env:"DB_DRIVER" envDefault:"mongodb"`
+
 	// BookingsDB encapsulates the configuration of the database
 	// layer used by the service.
 	BookingsDB DBConfig
 
+	// BookingsMQDriver selects the message bus used by the service.
+	// Supported drivers are "rabbitmq" and "memory". The "memory"
+	// driver delivers messages in-process and is meant for tests and
+	// single-node deployments.
+	BookingsMQDriver string `env:"MQ_DRIVER" envDefault:"rabbitmq"`
+
 	// BusConfig encapsulates the configuration for the message
 	// bus used by the service.
 	BookingsMQ BusConfig
+
+	// OutboxRelayInterval is the time to wait between two polls of
+	// the outbox for messages that have to be published.
+	OutboxRelayInterval time.Duration `env:"OUTBOX_RELAY_INTERVAL" envDefault:"5s"`
+
+	// OutboxRetention is how long the messages that were published
+	// are kept in the outbox, before they are purged.
+	OutboxRetention time.Duration `env:"OUTBOX_RETENTION" envDefault:"24h"`
+
+	// OutboxMaxAttempts is the maximum number of attempts to publish
+	// a message from the outbox, before it is parked.
+	OutboxMaxAttempts int `env:"OUTBOX_MAX_ATTEMPTS" envDefault:"10"`
+
+	// OutboxPublishTimeout is the maximum time that publishing a
+	// message from the outbox is allowed to take.
+	OutboxPublishTimeout time.Duration `env:"OUTBOX_PUBLISH_TIMEOUT" envDefault:"10s"`
+
+	// HoldTTL is the time for which a seat hold is valid.
+	HoldTTL time.Duration `env:"HOLD_TTL" envDefault:"10m"`
+
+	// HoldReaperInterval is the time to wait between two checks for
+	// expired seat holds.
+	HoldReaperInterval time.Duration `env:"HOLD_REAPER_INTERVAL" envDefault:"30s"`
+
+	// IdempotencyKeyTTL is the time for which an idempotency key and
+	// the recorded response of its request are stored.
+	IdempotencyKeyTTL time.Duration `env:"IDEMPOTENCY_KEY_TTL" envDefault:"24h"`
+
+	// ConsumerMaxAttempts is the maximum number of attempts to handle
+	// a received message, before it is dead-lettered.
+	ConsumerMaxAttempts int `env:"CONSUMER_MAX_ATTEMPTS" envDefault:"5"`
+
+	// ConsumerInitialBackoff is the time to wait before retrying a
+	// failed message for the first time. The time doubles with every
+	// retry, up to ConsumerMaxBackoff.
+	ConsumerInitialBackoff time.Duration `env:"CONSUMER_INITIAL_BACKOFF" envDefault:"200ms"`
+	ConsumerMaxBackoff     time.Duration `env:"CONSUMER_MAX_BACKOFF" envDefault:"10s"`
+
+	// HealthCheckTimeout is the maximum time that the readiness
+	// check of a dependency is allowed to take.
+	HealthCheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
+
+	// HealthCheckInterval is the time to wait between two updates of
+	// the status of the grpc health service.
+	HealthCheckInterval time.Duration `env:"HEALTH_CHECK_INTERVAL" envDefault:"5s"`
+
+	// ShutdownTimeout is the maximum time to wait for the in-flight
+	// event handlers and the background workers to finish, once the
+	// service is shutting down.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"15s"`
+
+	// OpenAPIValidation enables the validation of the requests and
+	// the responses of the rest api against the OpenAPI document.
+	// The responses are buffered, therefore it is meant for tests.
+	OpenAPIValidation bool `env:"OPENAPI_VALIDATION" envDefault:"false"`
+
+	// Auth encapsulates the configuration of the authentication of
+	// the callers of the service.
+	Auth AuthConfig
+}
+
+// AuthConfig encapsulates the configuration of the authentication of the
+// callers of the service. Callers authenticate with bearer json web tokens,
+// which are verified with the configured keys.
+type AuthConfig struct {
+	// Enabled enables the authentication. At least one key must be
+	// configured if the authentication is enabled.
+	Enabled bool `env:"AUTH_ENABLED" envDefault:"false"`
+
+	// HMACSecret is the shared secret of HS256 tokens.
+	HMACSecret string `env:"AUTH_HMAC_SECRET"`
+
+	// RSAPublicKeyFile is the path to a PEM encoded RSA public key
+	// for RS256 tokens.
+	RSAPublicKeyFile string `env:"AUTH_RSA_PUBLIC_KEY_FILE"`
+
+	// JWKSFile is the path to a local json web key set.
+	JWKSFile string `env:"AUTH_JWKS_FILE"`
+
+	// Issuer and Audience are the expected "iss" and "aud" claims
+	// of the tokens. They are not checked if empty.
+	Issuer   string `env:"AUTH_ISSUER"`
+	Audience string `env:"AUTH_AUDIENCE"`
+
+	// AdminRole is the role, listed in the "roles" claim of a token,
+	// which grants access to the resources of all users and to the
+	// admin api.
+	AdminRole string `env:"AUTH_ADMIN_ROLE" envDefault:"admin"`
+
+	// Leeway is the clock skew tolerated when checking the validity
+	// period of a token.
+	Leeway time.Duration `env:"AUTH_LEEWAY" envDefault:"30s"`
+
+	// AnonymousAdmin opens the admin api to anonymous callers while
+	// the authentication is disabled. It is meant for development
+	// and tests, since the admin api is closed otherwise.
+	AnonymousAdmin bool `env:"AUTH_ANONYMOUS_ADMIN" envDefault:"false"`
 }
 
 // DBConfig encapsulates the configuration of the database layer
@@ -29,4 +142,18 @@ type BusConfig struct {
 	Port     int    `env:"RABBIT_MQ_PORT"`
 	Username string `env:"RABBIT_MQ_USERNAME"`
 	Password string `env:"RABBIT_MQ_PASSWORD"`
+
+	// QueuePrefix is prepended to the topics in order to form
+	// the names of the durable queues of the subscriptions.
+	QueuePrefix string `env:"RABBIT_MQ_QUEUE_PREFIX" envDefault:"booking-service"`
+
+	// Prefetch is the maximum number of unacknowledged messages
+	// delivered to every subscription.
+	Prefetch int `env:"RABBIT_MQ_PREFETCH" envDefault:"10"`
+
+	// ReconnectBackoff is the time to wait before reconnecting
+	// after the connection to the message bus is lost. The time
+	// doubles with every failed attempt, up to MaxReconnectBackoff.
+	ReconnectBackoff    time.Duration `env:"RABBIT_MQ_RECONNECT_BACKOFF" envDefault:"500ms"`
+	MaxReconnectBackoff time.Duration `env:"RABBIT_MQ_MAX_RECONNECT_BACKOFF" envDefault:"30s"`
 }
@@ -3,9 +3,13 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 
 	"github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/booking"
+	"github.com/eventscompass/booking-service/src/internal/consumer"
 	"github.com/eventscompass/service-framework/pubsub"
 	"github.com/eventscompass/service-framework/service"
 )
@@ -25,12 +29,24 @@ func (s *BookingService) Events() map[string]service.EventHandler {
 func (s *BookingService) initEvents() {
 	eventHandler := &eventHandler{
 		bookingsDB: s.bookingsDB,
+		bookings:   s.bookings,
 	}
 
 	// Associate an event handler function to every event.
-	s.events = map[string]service.EventHandler{
-		pubsub.EventCreatedTopic:    eventHandler.eventCreated,
-		pubsub.LocationCreatedTopic: eventHandler.locationCreated,
+	handlers := map[string]consumer.Handler{
+		pubsub.EventCreatedTopic:     eventHandler.eventCreated,
+		internal.EventUpdatedTopic:   eventHandler.eventUpdated,
+		internal.EventCancelledTopic: eventHandler.eventCancelled,
+		pubsub.LocationCreatedTopic:  eventHandler.locationCreated,
+		internal.UserCreatedTopic:    eventHandler.userCreated,
+	}
+
+	// The handlers run through the consumer, which retries failed messages
+	// and dead-letters the ones that cannot be handled.
+	s.events = make(map[string]service.EventHandler, len(handlers))
+	for topic, h := range handlers {
+		s.events[topic] = s.consumer.Handle(topic, h)
+		s.subscriptions.Expect(topic)
 	}
 }
 
@@ -38,49 +54,182 @@ func (s *BookingService) initEvents() {
 // subscribed will be handled by one of the handler methods.
 type eventHandler struct {
 	bookingsDB internal.BookingsContainer
+	bookings   *booking.Manager
+}
+
+// eventCreatedPayload extends the [pubsub.EventCreated] payload with the
+// capacity and the version of the event. Both are optional.
+type eventCreatedPayload struct {
+	pubsub.EventCreated
+	Capacity int   `json:"capacity"`
+	Version  int64 `json:"version"`
 }
 
-func (h *eventHandler) eventCreated(ctx context.Context, msg []byte) {
+// locationCreatedPayload extends the [pubsub.LocationCreated] payload with the
+// capacity and the version of the location. Both are optional.
+type locationCreatedPayload struct {
+	pubsub.LocationCreated
+	Capacity int   `json:"capacity"`
+	Version  int64 `json:"version"`
+}
+
+func (h *eventHandler) eventCreated(ctx context.Context, msg []byte) error {
 	slog.Info("received message", slog.String("topic", pubsub.EventCreatedTopic))
 
-	var payload pubsub.EventCreated
+	var payload eventCreatedPayload
 	if err := json.Unmarshal(msg, &payload); err != nil {
-		slog.Error("failed to unmarshal payload", err)
-		return
+		return consumer.Reject(fmt.Errorf("unmarshal payload: %w", err))
+	}
+	if payload.ID == "" {
+		return consumer.Reject(fmt.Errorf("%w: missing id", service.ErrBadRequest))
 	}
 
 	data := internal.Event{
 		ID:         payload.ID,
+		Name:       payload.Name,
 		LocationID: payload.LocationID,
+		Start:      payload.Start,
+		End:        payload.End,
+		Capacity:   payload.Capacity,
+		Version:    payload.Version,
 	}
-	if err := h.bookingsDB.Create(ctx, internal.EventsCollection, data); err != nil {
-		slog.Error(
-			"failed to add to db",
-			slog.Any("message", data),
-			slog.String("error", err.Error()),
-		)
+	if data.Capacity == 0 {
+		data.Capacity = h.locationCapacity(ctx, payload.LocationID)
+	}
+	return upsertResult(data, h.bookingsDB.UpsertEvent(ctx, data))
+}
+
+func (h *eventHandler) eventUpdated(ctx context.Context, msg []byte) error {
+	slog.Info("received message", slog.String("topic", internal.EventUpdatedTopic))
+
+	var payload internal.EventUpdated
+	if err := json.Unmarshal(msg, &payload); err != nil {
+		return consumer.Reject(fmt.Errorf("unmarshal payload: %w", err))
+	}
+	if payload.ID == "" {
+		return consumer.Reject(fmt.Errorf("%w: missing id", service.ErrBadRequest))
+	}
+
+	data := internal.Event{
+		ID:         payload.ID,
+		Name:       payload.Name,
+		LocationID: payload.LocationID,
+		Start:      payload.Start,
+		End:        payload.End,
+		Capacity:   payload.Capacity,
+		Version:    payload.Version,
+	}
+
+	// Keep the capacity of the event if the update does not change it,
+	// since it might have been set by an admin.
+	if data.Capacity == 0 {
+		data.Capacity = h.eventCapacity(ctx, payload.ID, payload.LocationID)
+	}
+	return upsertResult(data, h.bookingsDB.UpsertEvent(ctx, data))
+}
+
+func (h *eventHandler) eventCancelled(ctx context.Context, msg []byte) error {
+	slog.Info("received message", slog.String("topic", internal.EventCancelledTopic))
+
+	var payload internal.EventCancelled
+	if err := json.Unmarshal(msg, &payload); err != nil {
+		return consumer.Reject(fmt.Errorf("unmarshal payload: %w", err))
+	}
+	if payload.ID == "" {
+		return consumer.Reject(fmt.Errorf("%w: missing id", service.ErrBadRequest))
+	}
+
+	if err := h.bookings.CancelEvent(ctx, payload.ID); err != nil {
+		return fmt.Errorf("cancel event %q: %w", payload.ID, err)
 	}
+	return nil
 }
 
-func (h *eventHandler) locationCreated(ctx context.Context, msg []byte) {
+func (h *eventHandler) locationCreated(ctx context.Context, msg []byte) error {
 	slog.Info("received message", slog.String("topic", pubsub.LocationCreatedTopic))
 
-	var payload pubsub.LocationCreated
+	var payload locationCreatedPayload
 	if err := json.Unmarshal(msg, &payload); err != nil {
-		slog.Error("failed to unmarshal payload", err)
-		return
+		return consumer.Reject(fmt.Errorf("unmarshal payload: %w", err))
+	}
+	if payload.ID == "" {
+		return consumer.Reject(fmt.Errorf("%w: missing id", service.ErrBadRequest))
 	}
 
 	data := internal.Location{
+		ID:       payload.ID,
+		Name:     payload.Name,
+		Capacity: payload.Capacity,
+		Version:  payload.Version,
+	}
+	return upsertResult(data, h.bookingsDB.UpsertLocation(ctx, data))
+}
+
+// userCreated stores the created user, so that bookings can reference it. A
+// user that is already stored is kept, thus redelivered messages are
+// acknowledged without changes.
+func (h *eventHandler) userCreated(ctx context.Context, msg []byte) error {
+	slog.Info("received message", slog.String("topic", internal.UserCreatedTopic))
+
+	var payload internal.UserCreated
+	if err := json.Unmarshal(msg, &payload); err != nil {
+		return consumer.Reject(fmt.Errorf("unmarshal payload: %w", err))
+	}
+	if payload.ID == "" {
+		return consumer.Reject(fmt.Errorf("%w: missing id", service.ErrBadRequest))
+	}
+
+	data := internal.User{
 		ID:   payload.ID,
 		Name: payload.Name,
 	}
-	err := h.bookingsDB.Create(ctx, internal.LocationsCollection, data)
-	if err != nil {
-		slog.Error(
-			"failed to add to db",
+	return upsertResult(data, h.bookingsDB.Create(ctx, internal.UsersCollection, data))
+}
+
+// upsertResult returns the result of storing the data received with a
+// message. Messages can be redelivered or arrive out of order, thus a message
+// carrying the same or an older version than the stored one is expected and
+// acknowledged without changes.
+func upsertResult(data any, err error) error {
+	if errors.Is(err, service.ErrAlreadyExists) {
+		slog.Info(
+			"skipping duplicate or stale message",
 			slog.Any("message", data),
+			slog.String("reason", err.Error()),
+		)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("store %T: %w", data, err)
+	}
+	return nil
+}
+
+// eventCapacity returns the capacity of the stored event with the given id. If
+// the event cannot be retrieved, then the capacity of its location is returned.
+func (h *eventHandler) eventCapacity(ctx context.Context, id, locationID string) int {
+	elem, err := h.bookingsDB.GetByID(ctx, internal.EventsCollection, id)
+	if event, ok := elem.(internal.Event); err == nil && ok {
+		return event.Capacity
+	}
+	return h.locationCapacity(ctx, locationID)
+}
+
+// locationCapacity returns the capacity of the location with the given id. If
+// the location cannot be retrieved, then zero, i.e. no capacity, is returned.
+func (h *eventHandler) locationCapacity(ctx context.Context, id string) int {
+	elem, err := h.bookingsDB.GetByID(ctx, internal.LocationsCollection, id)
+	if err != nil {
+		slog.Warn(
+			"failed to get location capacity",
+			slog.String("location_id", id),
 			slog.String("error", err.Error()),
 		)
+		return 0
+	}
+	location, ok := elem.(internal.Location)
+	if !ok {
+		return 0
 	}
+	return location.Capacity
 }
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/auth"
+	"github.com/eventscompass/booking-service/src/internal/booking"
+	"github.com/eventscompass/booking-service/src/internal/health"
+	"github.com/eventscompass/booking-service/src/internal/validation"
+	bookingv1 "github.com/eventscompass/booking-service/src/proto/booking/v1"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// GRPC implements the [service.CloudService] interface.
+func (s *BookingService) GRPC() *grpc.Server {
+	return s.grpcServer
+}
+
+// initGRPC initializes the server for the grpc part of the service. Besides the
+// booking service, the server serves the standard health service, which
+// reflects the readiness of the service, and the reflection service. The
+// callers of the booking service are authenticated if enabled.
+func (s *BookingService) initGRPC(ctx context.Context) {
+	authn := &grpcAuth{verifier: s.verifier}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryErrors, authn.unary),
+		grpc.ChainStreamInterceptor(streamErrors, authn.stream),
+	)
+	s.stopping = make(chan struct{})
+	bookingv1.RegisterBookingServiceServer(srv, &grpcHandler{
+		bookings: s.bookings,
+		done:     s.stopping,
+	})
+	context.AfterFunc(ctx, s.stopGRPC)
+
+	// The health status is updated in the background. The goroutine
+	// stops once the service is shut down.
+	hs := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	s.goWorker(func() { runGRPCHealth(ctx, hs, s.readiness, s.cfg.HealthCheckInterval) })
+
+	reflection.Register(srv)
+	s.grpcServer = srv
+}
+
+// stopGRPC ends the streams of the grpc server, which would otherwise block its
+// graceful stop. If the server has not stopped gracefully within the shutdown
+// timeout, e.g. because of a hanging request, then it is stopped forcibly. It
+// is called once the context passed to Init is cancelled, i.e. on a stop
+// signal, and on [BookingService.Shutdown]. It is safe to call more than once.
+func (s *BookingService) stopGRPC() {
+	s.stopOnce.Do(func() {
+		close(s.stopping)
+		time.AfterFunc(s.cfg.ShutdownTimeout, s.grpcServer.Stop)
+	})
+}
+
+// runGRPCHealth sets the status of the grpc health server according to the
+// readiness of the service, every interval, until the context is cancelled.
+// The status is then set to not serving. The first check is run after the
+// first interval, so that the subscriptions have time to start. This is a
+// blocking function.
+func runGRPCHealth(
+	ctx context.Context,
+	hs *grpchealth.Server,
+	readiness *health.Checker,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			hs.Shutdown()
+			return
+		case <-ticker.C:
+		}
+
+		st := healthpb.HealthCheckResponse_SERVING
+		if readiness.Run(ctx).Status != health.StatusUp {
+			st = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		hs.SetServingStatus("", st)
+		hs.SetServingStatus(bookingv1.BookingService_ServiceDesc.ServiceName, st)
+	}
+}
+
+// grpcHandler handles grpc requests. It is the bridge between the grpc api and
+// the business logic, just like the [restHandler] is for the rest api.
+type grpcHandler struct {
+	bookingv1.UnimplementedBookingServiceServer
+
+	bookings *booking.Manager
+
+	// done is closed once the servers are stopping, in order to end the
+	// streams, which would otherwise block the graceful stop of the
+	// server.
+	done <-chan struct{}
+}
+
+// CreateBooking implements the [bookingv1.BookingServiceServer] interface.
+func (h *grpcHandler) CreateBooking(
+	ctx context.Context,
+	req *bookingv1.CreateBookingRequest,
+) (*bookingv1.Booking, error) {
+	// Decode the request.
+	b := internal.Booking{
+		UserID:  req.GetUserId(),
+		EventID: req.GetEventId(),
+	}
+	if req.Date != nil {
+		if err := req.GetDate().CheckValid(); err != nil {
+			return nil, &validation.Error{Violations: []validation.Violation{
+				{Field: "date", Message: "must be a valid timestamp"},
+			}}
+		}
+		b.Date = req.GetDate().AsTime()
+	}
+
+	// Create the booking.
+	slog.Info("request to create booking", slog.Any("booking", b))
+	if err := h.bookings.Create(ctx, &b); err != nil {
+		return nil, err //nolint:wrapcheck // mapped to a status by the interceptor
+	}
+	slog.Info("booking successfully created", slog.String("id", b.ID))
+
+	return toProto(&b), nil
+}
+
+// GetBooking implements the [bookingv1.BookingServiceServer] interface.
+func (h *grpcHandler) GetBooking(
+	ctx context.Context,
+	req *bookingv1.GetBookingRequest,
+) (*bookingv1.Booking, error) {
+	b, err := h.bookings.Get(ctx, req.GetId())
+	if err != nil {
+		return nil, err //nolint:wrapcheck // mapped to a status by the interceptor
+	}
+	return toProto(b), nil
+}
+
+// ListBookings implements the [bookingv1.BookingServiceServer] interface.
+func (h *grpcHandler) ListBookings(
+	ctx context.Context,
+	req *bookingv1.ListBookingsRequest,
+) (*bookingv1.ListBookingsResponse, error) {
+	// Decode the request.
+	q := &booking.ListQuery{
+		UserID:  req.GetUserId(),
+		EventID: req.GetEventId(),
+		Status:  fromProtoStatus(req.GetStatus()),
+		Cursor:  req.GetCursor(),
+		Limit:   int(req.GetLimit()),
+	}
+	if req.From != nil {
+		q.From = req.GetFrom().AsTime()
+	}
+	if req.To != nil {
+		q.To = req.GetTo().AsTime()
+	}
+
+	// List the bookings.
+	slog.Info("request to list bookings", slog.Any("query", q))
+	page, err := h.bookings.List(ctx, q)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // mapped to a status by the interceptor
+	}
+
+	resp := &bookingv1.ListBookingsResponse{
+		Bookings:   make([]*bookingv1.Booking, len(page.Bookings)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Bookings {
+		resp.Bookings[i] = toProto(&page.Bookings[i])
+	}
+	return resp, nil
+}
+
+// CancelBooking implements the [bookingv1.BookingServiceServer] interface.
+func (h *grpcHandler) CancelBooking(
+	ctx context.Context,
+	req *bookingv1.CancelBookingRequest,
+) (*bookingv1.Booking, error) {
+	// Cancel the booking.
+	slog.Info("request to cancel booking", slog.String("id", req.GetId()))
+	if err := h.bookings.Cancel(ctx, req.GetId()); err != nil {
+		return nil, err //nolint:wrapcheck // mapped to a status by the interceptor
+	}
+	slog.Info("booking successfully cancelled")
+
+	// Respond with the cancelled booking.
+	b, err := h.bookings.Get(ctx, req.GetId())
+	if err != nil {
+		return nil, err //nolint:wrapcheck // mapped to a status by the interceptor
+	}
+	return toProto(b), nil
+}
+
+// WatchBookings implements the [bookingv1.BookingServiceServer] interface.
+func (h *grpcHandler) WatchBookings(
+	req *bookingv1.WatchBookingsRequest,
+	stream bookingv1.BookingService_WatchBookingsServer,
+) error {
+	ctx := stream.Context()
+	slog.Info(
+		"request to watch bookings",
+		slog.String("user_id", req.GetUserId()),
+		slog.String("event_id", req.GetEventId()),
+	)
+
+	// The headers are sent once the changes are watched, so that the client
+	// can tell from when on the changes are streamed.
+	changes := h.bookings.Watch(ctx)
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err //nolint:wrapcheck // the stream is broken
+	}
+	for {
+		var id string
+		select {
+		case <-h.done:
+			return status.Error(codes.Unavailable, "service is shutting down")
+		case v, ok := <-changes:
+			if !ok && ctx.Err() != nil {
+				return ctx.Err() //nolint:wrapcheck // mapped to a status by the interceptor
+			}
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher fell behind")
+			}
+			id = v
+		}
+
+		b, err := h.bookings.Get(ctx, id)
+		if errors.Is(err, service.ErrNotFound) || errors.Is(err, auth.ErrForbidden) {
+			continue // the booking is gone, or belongs to another user
+		}
+		if err != nil {
+			return err //nolint:wrapcheck // mapped to a status by the interceptor
+		}
+		if (req.GetUserId() != "" && b.UserID != req.GetUserId()) ||
+			(req.GetEventId() != "" && b.EventID != req.GetEventId()) {
+			continue
+		}
+		if err := stream.Send(toProto(b)); err != nil {
+			return err //nolint:wrapcheck // the stream is broken
+		}
+	}
+}
+
+// statuses maps every booking status to its protobuf enum value.
+var statuses = map[internal.BookingStatus]bookingv1.BookingStatus{
+	internal.StatusPending:   bookingv1.BookingStatus_BOOKING_STATUS_PENDING,
+	internal.StatusConfirmed: bookingv1.BookingStatus_BOOKING_STATUS_CONFIRMED,
+	internal.StatusCancelled: bookingv1.BookingStatus_BOOKING_STATUS_CANCELLED,
+	internal.StatusCheckedIn: bookingv1.BookingStatus_BOOKING_STATUS_CHECKED_IN,
+	internal.StatusExpired:   bookingv1.BookingStatus_BOOKING_STATUS_EXPIRED,
+}
+
+// fromProtoStatus converts the protobuf enum value of a booking status. The
+// unspecified value converts to the empty status.
+func fromProtoStatus(s bookingv1.BookingStatus) internal.BookingStatus {
+	for status, v := range statuses {
+		if v == s {
+			return status
+		}
+	}
+	return ""
+}
+
+// toProto converts the booking to its protobuf message.
+func toProto(b *internal.Booking) *bookingv1.Booking {
+	pb := &bookingv1.Booking{
+		Id:        b.ID,
+		UserId:    b.UserID,
+		EventId:   b.EventID,
+		Date:      timestamppb.New(b.Date),
+		Status:    statuses[b.Status],
+		CreatedAt: timestamppb.New(b.CreatedAt),
+		UpdatedAt: timestamppb.New(b.UpdatedAt),
+	}
+	if b.ExpiresAt != nil {
+		pb.ExpiresAt = timestamppb.New(*b.ExpiresAt)
+	}
+	return pb
+}
+
+// unaryErrors is a [grpc.UnaryServerInterceptor] which converts the errors
+// returned by the handlers into grpc status errors.
+func unaryErrors(
+	ctx context.Context,
+	req any,
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp, nil
+}
+
+// streamErrors is a [grpc.StreamServerInterceptor] which converts the errors
+// returned by the handlers into grpc status errors.
+func streamErrors(
+	srv any,
+	ss grpc.ServerStream,
+	_ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if err := handler(srv, ss); err != nil {
+		return grpcError(err)
+	}
+	return nil
+}
+
+// grpcAuth authenticates the callers of the booking service with the bearer
+// tokens in the "authorization" metadata of their calls, just like the rest
+// api does with the Authorization header. The health and the reflection
+// services are not authenticated.
+type grpcAuth struct {
+	// verifier is nil if the authentication is disabled.
+	verifier *auth.Verifier
+}
+
+// authenticate returns a copy of the context which carries the principal of
+// the caller of the method. This function returns [auth.ErrUnauthenticated]
+// if the caller cannot be authenticated.
+func (a *grpcAuth) authenticate(ctx context.Context, method string) (context.Context, error) {
+	prefix := "/" + bookingv1.BookingService_ServiceDesc.ServiceName + "/"
+	if a.verifier == nil || !strings.HasPrefix(method, prefix) {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var header string
+	if values := md.Get("authorization"); len(values) > 0 {
+		header = values[0]
+	}
+	token, err := auth.BearerToken(header)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // mapped to a status by the interceptor
+	}
+	p, err := a.verifier.Verify(token)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // mapped to a status by the interceptor
+	}
+	return auth.WithPrincipal(ctx, p), nil
+}
+
+// unary is a [grpc.UnaryServerInterceptor] which authenticates the caller.
+func (a *grpcAuth) unary(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req) //nolint:wrapcheck // mapped to a status by the outer interceptor
+}
+
+// stream is a [grpc.StreamServerInterceptor] which authenticates the caller.
+func (a *grpcAuth) stream(
+	srv any,
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	//nolint:wrapcheck // mapped to a status by the outer interceptor
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream is a [grpc.ServerStream] whose context carries the
+// principal of the caller.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context implements the [grpc.ServerStream] interface.
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcClass is a class of errors which map to the same grpc code.
+type grpcClass struct {
+	err  error
+	code codes.Code
+}
+
+// grpcClasses maps every class of errors to its grpc code. The classes are
+// checked in order, just like the classes of the http problem details.
+var grpcClasses = []grpcClass{
+	{context.Canceled, codes.Canceled},
+	{context.DeadlineExceeded, codes.DeadlineExceeded},
+	{auth.ErrUnauthenticated, codes.Unauthenticated},
+	{auth.ErrForbidden, codes.PermissionDenied},
+	{service.ErrBadRequest, codes.InvalidArgument},
+	{service.ErrNotAllowed, codes.FailedPrecondition},
+	{service.ErrNotFound, codes.NotFound},
+	{service.ErrAlreadyExists, codes.AlreadyExists},
+	{service.ErrSpaceFull, codes.ResourceExhausted},
+	{service.ErrTimeOut, codes.DeadlineExceeded},
+	{service.ErrConnectionClosed, codes.Unavailable},
+}
+
+// grpcError converts the error into a grpc status error. Errors that already
+// carry a status are returned as they are. The messages of internal errors are
+// not disclosed to the client, and the violations of validation errors are
+// attached as [errdetails.BadRequest] details.
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	code, class := codes.Internal, error(nil)
+	for _, c := range grpcClasses {
+		if errors.Is(err, c.err) {
+			code, class = c.code, c.err
+			break
+		}
+	}
+	if code == codes.Internal {
+		slog.Error("unexpected error while handling request", slog.String("error", err.Error()))
+		return status.Error(code, "unexpected error")
+	}
+	slog.Info("request failed", slog.String("code", code.String()), slog.String("error", err.Error()))
+
+	// The message starts from the classification of the error, since the
+	// messages of the callers describe the call stack.
+	msg := err.Error()
+	if i := strings.Index(msg, class.Error()); i >= 0 {
+		msg = msg[i:]
+	}
+	st := status.New(code, msg)
+	var invalid *validation.Error
+	if errors.As(err, &invalid) {
+		br := &errdetails.BadRequest{}
+		for _, v := range invalid.Violations {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Message,
+			})
+		}
+		if withDetails, err := st.WithDetails(br); err == nil {
+			st = withDetails
+		} else {
+			slog.Warn("failed to attach error details", slog.String("error", err.Error()))
+		}
+	}
+	return st.Err() //nolint:wrapcheck // the status is the error of the rpc
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/eventscompass/booking-service/src/internal"
+	bookingv1 "github.com/eventscompass/booking-service/src/proto/booking/v1"
+)
+
+// testHMACSecret is the secret with which the tokens of the tests are signed.
+const testHMACSecret = "0123456789abcdef0123456789abcdef"
+
+// newTestGRPC serves the grpc api of a service from [newTestService] over an
+// in-memory connection, and returns the connection along with the service.
+func newTestGRPC(t *testing.T, env map[string]string) (*grpc.ClientConn, *BookingService) {
+	t.Helper()
+	s := newTestService(t, env)
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = s.grpcServer.Serve(lis) }()
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial grpc server: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		s.grpcServer.Stop()
+	})
+	return conn, s
+}
+
+// withToken returns a copy of the context, whose calls carry a bearer token of
+// the given user, signed with [testHMACSecret].
+func withToken(ctx context.Context, t *testing.T, subject string) context.Context {
+	t.Helper()
+	segment := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("encode token: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." +
+		segment(map[string]any{"sub": subject, "exp": time.Now().Add(time.Hour).Unix()})
+	mac := hmac.New(sha256.New, []byte(testHMACSecret))
+	mac.Write([]byte(signed))
+	token := signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestGRPCErrors(t *testing.T) {
+	tests := map[string]struct {
+		call       func(context.Context, bookingv1.BookingServiceClient) error
+		wantCode   codes.Code
+		wantMsg    string
+		wantFields []string
+	}{
+		"Validation": {
+			call: func(ctx context.Context, c bookingv1.BookingServiceClient) error {
+				_, err := c.CreateBooking(ctx, &bookingv1.CreateBookingRequest{})
+				return err //nolint:wrapcheck // the status is checked by the test
+			},
+			wantCode:   codes.InvalidArgument,
+			wantMsg:    "bad request",
+			wantFields: []string{"user_id", "event_id"},
+		},
+		"NotFound": {
+			call: func(ctx context.Context, c bookingv1.BookingServiceClient) error {
+				_, err := c.GetBooking(ctx, &bookingv1.GetBookingRequest{Id: "missing"})
+				return err //nolint:wrapcheck // the status is checked by the test
+			},
+			wantCode: codes.NotFound,
+			wantMsg:  "not found",
+		},
+		"SpaceFull": {
+			call: func(ctx context.Context, c bookingv1.BookingServiceClient) error {
+				req := &bookingv1.CreateBookingRequest{UserId: "u1", EventId: "e1"}
+				if _, err := c.CreateBooking(ctx, req); err != nil {
+					return err //nolint:wrapcheck // the status is checked by the test
+				}
+				req.UserId = "u2"
+				_, err := c.CreateBooking(ctx, req)
+				return err //nolint:wrapcheck // the status is checked by the test
+			},
+			wantCode: codes.ResourceExhausted,
+			wantMsg:  "no space",
+		},
+		"Internal": {
+			// The booking "corrupt" is stored as a user, which cannot
+			// happen unless the database is corrupt.
+			call: func(ctx context.Context, c bookingv1.BookingServiceClient) error {
+				_, err := c.GetBooking(ctx, &bookingv1.GetBookingRequest{Id: "corrupt"})
+				return err //nolint:wrapcheck // the status is checked by the test
+			},
+			wantCode: codes.Internal,
+			wantMsg:  "unexpected error",
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			conn, s := newTestGRPC(t, nil)
+			corrupt := internal.User{ID: "corrupt", Name: "booking-service:secret@db"}
+			if err := s.bookingsDB.Create(ctx, internal.BookingsCollection, corrupt); err != nil {
+				t.Fatalf("create corrupt booking: %v", err)
+			}
+
+			err := test.call(ctx, bookingv1.NewBookingServiceClient(conn))
+			st, ok := status.FromError(err)
+			if !ok || st.Code() != test.wantCode {
+				t.Fatalf("want code %v, got %v", test.wantCode, err)
+			}
+			if !strings.HasPrefix(st.Message(), test.wantMsg) {
+				t.Errorf("want a message starting with %q, got %q", test.wantMsg, st.Message())
+			}
+			if test.wantCode == codes.Internal && st.Message() != test.wantMsg {
+				t.Errorf("want the internal error to be hidden, got %q", st.Message())
+			}
+
+			var fields []string
+			for _, d := range st.Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok {
+					for _, v := range br.GetFieldViolations() {
+						fields = append(fields, v.GetField())
+					}
+				}
+			}
+			if strings.Join(fields, ",") != strings.Join(test.wantFields, ",") {
+				t.Errorf("want violations of %v, got %v", test.wantFields, fields)
+			}
+		})
+	}
+}
+
+func TestGRPCAuth(t *testing.T) {
+	tests := map[string]struct {
+		call     func(context.Context, *grpc.ClientConn) error
+		subject  string
+		wantCode codes.Code
+	}{
+		"BookingWithoutToken": {
+			call:     getBooking,
+			wantCode: codes.Unauthenticated,
+		},
+		"BookingWithInvalidToken": {
+			call: func(ctx context.Context, conn *grpc.ClientConn) error {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer a.b.c")
+				return getBooking(ctx, conn)
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		"BookingOfOtherUser": {
+			call:     getBooking,
+			subject:  "u2",
+			wantCode: codes.PermissionDenied,
+		},
+		"BookingOfCaller": {
+			call:     getBooking,
+			subject:  "u1",
+			wantCode: codes.OK,
+		},
+		"WatchWithoutToken": {
+			call: func(ctx context.Context, conn *grpc.ClientConn) error {
+				c := bookingv1.NewBookingServiceClient(conn)
+				stream, err := c.WatchBookings(ctx, &bookingv1.WatchBookingsRequest{})
+				if err != nil {
+					return err //nolint:wrapcheck // the status is checked by the test
+				}
+				_, err = stream.Recv()
+				return err //nolint:wrapcheck // the status is checked by the test
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		"HealthWithoutToken": {
+			call: func(ctx context.Context, conn *grpc.ClientConn) error {
+				_, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+				return err //nolint:wrapcheck // the status is checked by the test
+			},
+			wantCode: codes.OK,
+		},
+		"ReflectionWithoutToken": {
+			call: func(ctx context.Context, conn *grpc.ClientConn) error {
+				c := reflectionpb.NewServerReflectionClient(conn)
+				stream, err := c.ServerReflectionInfo(ctx)
+				if err != nil {
+					return err //nolint:wrapcheck // the status is checked by the test
+				}
+				err = stream.Send(&reflectionpb.ServerReflectionRequest{
+					MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+				})
+				if err != nil {
+					return err //nolint:wrapcheck // the status is checked by the test
+				}
+				_, err = stream.Recv()
+				return err //nolint:wrapcheck // the status is checked by the test
+			},
+			wantCode: codes.OK,
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			conn, s := newTestGRPC(t, map[string]string{
+				"AUTH_ENABLED":     "true",
+				"AUTH_HMAC_SECRET": testHMACSecret,
+			})
+			b := internal.Booking{ID: "b1", UserID: "u1", EventID: "e1", Status: internal.StatusConfirmed}
+			if err := s.bookingsDB.Create(ctx, internal.BookingsCollection, b); err != nil {
+				t.Fatalf("create booking: %v", err)
+			}
+
+			if test.subject != "" {
+				ctx = withToken(ctx, t, test.subject)
+			}
+			err := test.call(ctx, conn)
+			if code := status.Code(err); code != test.wantCode {
+				t.Errorf("want code %v, got %v", test.wantCode, err)
+			}
+		})
+	}
+}
+
+// getBooking gets the booking "b1" over the connection.
+func getBooking(ctx context.Context, conn *grpc.ClientConn) error {
+	c := bookingv1.NewBookingServiceClient(conn)
+	_, err := c.GetBooking(ctx, &bookingv1.GetBookingRequest{Id: "b1"})
+	return err //nolint:wrapcheck // the status is checked by the test
+}
+
+func TestWatchBookings(t *testing.T) {
+	tests := map[string]struct {
+		req *bookingv1.WatchBookingsRequest
+
+		// create are the bookings that are created while watching, as
+		// pairs of user and event.
+		create [][2]string
+
+		// want is the pair of the first streamed booking.
+		want [2]string
+	}{
+		"All": {
+			req:    &bookingv1.WatchBookingsRequest{},
+			create: [][2]string{{"u2", "e2"}, {"u1", "e2"}},
+			want:   [2]string{"u2", "e2"},
+		},
+		"ByUser": {
+			req:    &bookingv1.WatchBookingsRequest{UserId: "u1"},
+			create: [][2]string{{"u2", "e2"}, {"u1", "e2"}},
+			want:   [2]string{"u1", "e2"},
+		},
+		"ByEvent": {
+			req:    &bookingv1.WatchBookingsRequest{EventId: "e2"},
+			create: [][2]string{{"u1", "e1"}, {"u2", "e2"}},
+			want:   [2]string{"u2", "e2"},
+		},
+		"ByUserAndEvent": {
+			req:    &bookingv1.WatchBookingsRequest{UserId: "u1", EventId: "e2"},
+			create: [][2]string{{"u1", "e1"}, {"u2", "e2"}, {"u1", "e2"}},
+			want:   [2]string{"u1", "e2"},
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			conn, s := newTestGRPC(t, nil)
+			start := time.Now().Add(time.Hour).UTC()
+			e2 := internal.Event{
+				ID: "e2", Name: "Play", Start: start, End: start.Add(time.Hour), Capacity: 10,
+			}
+			if err := s.bookingsDB.Create(ctx, internal.EventsCollection, e2); err != nil {
+				t.Fatalf("create event: %v", err)
+			}
+
+			c := bookingv1.NewBookingServiceClient(conn)
+			stream, err := c.WatchBookings(ctx, test.req)
+			if err != nil {
+				t.Fatalf("watch bookings: %v", err)
+			}
+			// The headers are received once the changes are watched.
+			if _, err := stream.Header(); err != nil {
+				t.Fatalf("receive headers: %v", err)
+			}
+
+			for _, pair := range test.create {
+				req := &bookingv1.CreateBookingRequest{UserId: pair[0], EventId: pair[1]}
+				if _, err := c.CreateBooking(ctx, req); err != nil {
+					t.Fatalf("create booking %v: %v", pair, err)
+				}
+			}
+
+			// The changes are streamed in order, thus the bookings that
+			// are filtered out would be received first.
+			b, err := stream.Recv()
+			if err != nil {
+				t.Fatalf("receive booking: %v", err)
+			}
+			if got := [2]string{b.GetUserId(), b.GetEventId()}; got != test.want {
+				t.Errorf("want booking %v, got %v", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+// ErrUnauthenticated classifies the errors of requests whose caller cannot be
+// authenticated, because the bearer token is missing or invalid. The framework
+// does not define such an error, since authentication is up to the service.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// ErrForbidden classifies the errors of requests whose caller is not allowed
+// to access the resource. It is always accompanied by [service.ErrNotAllowed],
+// so that the error is handled as such by callers that do not know about
+// authorization.
+var ErrForbidden = fmt.Errorf("%w: forbidden", service.ErrNotAllowed)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject is the id of the user making the request.
+	Subject string
+
+	// Admin tells whether the caller has the admin role, which
+	// grants access to the resources of all users.
+	Admin bool
+}
+
+// principalKey is the context key under which the principal is stored.
+type principalKey struct{}
+
+// WithPrincipal returns a copy of the context which carries the principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal carried by the context, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Authorize checks that the caller may access the resources of the user with
+// the given id, which is the case for the user itself and for admins. Contexts
+// without a principal are not restricted, since they belong either to requests
+// made while authentication is disabled, or to the service itself. This
+// function returns [ErrForbidden] if the caller is another user.
+func Authorize(ctx context.Context, userID string) error {
+	p, ok := FromContext(ctx)
+	if !ok || p.Admin || p.Subject == userID {
+		return nil
+	}
+	return fmt.Errorf("%w: caller %q cannot access the resources of another user",
+		ErrForbidden, p.Subject)
+}
+
+// RequireAdmin checks that the caller is an admin. Unlike with [Authorize],
+// contexts without a principal are rejected, so that the admin api is not open
+// to anonymous callers while authentication is disabled. This function returns
+// [ErrUnauthenticated] if there is no principal, and [ErrForbidden] if the
+// caller is not an admin.
+func RequireAdmin(ctx context.Context) error {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%w: caller is not authenticated", ErrUnauthenticated)
+	}
+	if !p.Admin {
+		return fmt.Errorf("%w: caller %q is not an admin", ErrForbidden, p.Subject)
+	}
+	return nil
+}
+
+// BearerToken extracts the token from the value of an Authorization header.
+// This function returns [ErrUnauthenticated] if the value does not hold a
+// bearer token.
+func BearerToken(header string) (string, error) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", fmt.Errorf("%w: missing bearer token", ErrUnauthenticated)
+	}
+	return strings.TrimSpace(token), nil
+}
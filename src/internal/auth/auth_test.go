@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+func TestAuthorize(t *testing.T) {
+	tests := map[string]struct {
+		caller           *Principal
+		wantAuthorizeErr error
+		wantAdminErr     error
+	}{
+		"Anonymous": {
+			wantAdminErr: ErrUnauthenticated,
+		},
+		"Owner": {
+			caller:       &Principal{Subject: "u1"},
+			wantAdminErr: ErrForbidden,
+		},
+		"OtherUser": {
+			caller:           &Principal{Subject: "u2"},
+			wantAuthorizeErr: ErrForbidden,
+			wantAdminErr:     ErrForbidden,
+		},
+		"Admin": {
+			caller: &Principal{Subject: "a1", Admin: true},
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.caller != nil {
+				ctx = WithPrincipal(ctx, test.caller)
+			}
+			checks := map[string]struct {
+				err     error
+				wantErr error
+			}{
+				"Authorize":    {Authorize(ctx, "u1"), test.wantAuthorizeErr},
+				"RequireAdmin": {RequireAdmin(ctx), test.wantAdminErr},
+			}
+			for check, c := range checks {
+				if c.wantErr == nil && c.err != nil {
+					t.Errorf("%s: want no error, got %v", check, c.err)
+				}
+				if c.wantErr != nil && !errors.Is(c.err, c.wantErr) {
+					t.Errorf("%s: want error %v, got %v", check, c.wantErr, c.err)
+				}
+			}
+		})
+	}
+}
+
+func TestForbiddenIsNotAllowed(t *testing.T) {
+	err := Authorize(WithPrincipal(context.Background(), &Principal{Subject: "u2"}), "u1")
+	if !errors.Is(err, service.ErrNotAllowed) {
+		t.Errorf("want error %v, got %v", service.ErrNotAllowed, err)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := map[string]struct {
+		header    string
+		wantToken string
+	}{
+		"Bearer":     {header: "Bearer abc.def.ghi", wantToken: "abc.def.ghi"},
+		"LowerCase":  {header: "bearer abc.def.ghi", wantToken: "abc.def.ghi"},
+		"Padded":     {header: "Bearer  abc.def.ghi ", wantToken: "abc.def.ghi"},
+		"Missing":    {header: ""},
+		"Basic":      {header: "Basic dXNlcjpwYXNz"},
+		"NoToken":    {header: "Bearer "},
+		"NoSpace":    {header: "Bearerabc.def.ghi"},
+		"OnlyScheme": {header: "Bearer"},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			token, err := BearerToken(test.header)
+			if test.wantToken == "" {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Fatalf("want error %v, got %v and %q", ErrUnauthenticated, err, token)
+				}
+				return
+			}
+			if err != nil || token != test.wantToken {
+				t.Errorf("want token %q, got %q and %v", test.wantToken, token, err)
+			}
+		})
+	}
+}
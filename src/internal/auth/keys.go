@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+const (
+	// HS256 is the algorithm of tokens signed with HMAC using SHA-256.
+	HS256 = "HS256"
+
+	// RS256 is the algorithm of tokens signed with RSASSA-PKCS1-v1_5
+	// using SHA-256.
+	RS256 = "RS256"
+)
+
+const (
+	// minHMACSecret is the minimum size of an HMAC secret in bytes, which
+	// must be at least the size of the hash output.
+	minHMACSecret = 32
+
+	// minRSABits is the minimum size of an RSA key in bits.
+	minRSABits = 2048
+)
+
+// Key is a key which verifies the signatures of tokens. Every key verifies a
+// single algorithm, so that a token cannot choose how its signature is
+// verified.
+type Key struct {
+	// ID is the id of the key, which is matched against the "kid"
+	// header of the tokens. Keys without an id match every token.
+	ID string
+
+	// Algorithm is either [HS256] or [RS256].
+	Algorithm string
+
+	secret []byte
+	public *rsa.PublicKey
+}
+
+// NewHMACKey creates a [HS256] key from the shared secret. This function
+// returns [service.ErrUnexpected] if the secret is too short.
+func NewHMACKey(id string, secret []byte) (*Key, error) {
+	if len(secret) < minHMACSecret {
+		return nil, fmt.Errorf("%w: hmac secret must be at least %d bytes",
+			service.ErrUnexpected, minHMACSecret)
+	}
+	return &Key{ID: id, Algorithm: HS256, secret: secret}, nil
+}
+
+// NewRSAKey creates a [RS256] key from the public key. This function returns
+// [service.ErrUnexpected] if the key is too small.
+func NewRSAKey(id string, public *rsa.PublicKey) (*Key, error) {
+	if public.N.BitLen() < minRSABits {
+		return nil, fmt.Errorf("%w: rsa key must be at least %d bits",
+			service.ErrUnexpected, minRSABits)
+	}
+	return &Key{ID: id, Algorithm: RS256, public: public}, nil
+}
+
+// ParseRSAPublicKey parses a PEM encoded RSA public key, either in PKIX or in
+// PKCS #1 form. This function returns [service.ErrUnexpected] if the data does
+// not hold such a key.
+func ParseRSAPublicKey(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no pem block found", service.ErrUnexpected)
+	}
+
+	var public *rsa.PublicKey
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parse public key: %v", service.ErrUnexpected, err)
+		}
+		var ok bool
+		if public, ok = key.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("%w: public key is a %T, not an rsa key",
+				service.ErrUnexpected, key)
+		}
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parse public key: %v", service.ErrUnexpected, err)
+		}
+		public = key
+	default:
+		return nil, fmt.Errorf("%w: unsupported pem block %q", service.ErrUnexpected, block.Type)
+	}
+	return NewRSAKey("", public)
+}
+
+// jwk is a json web key, as defined by RFC 7517. Only the members of RSA and
+// symmetric keys are decoded.
+type jwk struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	K         string `json:"k"`
+}
+
+// ParseJWKS parses a json web key set. Keys which are not meant for verifying
+// signatures, or whose algorithm is not supported, are skipped. This function
+// returns [service.ErrUnexpected] if the set is malformed or holds no usable
+// key.
+func ParseJWKS(data []byte) ([]*Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%w: parse jwks: %v", service.ErrUnexpected, err)
+	}
+
+	var keys []*Key
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var (
+			key *Key
+			err error
+		)
+		switch {
+		case k.KeyType == "RSA" && (k.Algorithm == "" || k.Algorithm == RS256):
+			key, err = k.rsaKey()
+		case k.KeyType == "oct" && (k.Algorithm == "" || k.Algorithm == HS256):
+			key, err = k.hmacKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %d: %w", i, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: jwks holds no usable key", service.ErrUnexpected)
+	}
+	return keys, nil
+}
+
+func (k *jwk) rsaKey() (*Key, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode modulus: %v", service.ErrUnexpected, err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode exponent: %v", service.ErrUnexpected, err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("%w: invalid exponent", service.ErrUnexpected)
+	}
+	return NewRSAKey(k.ID, &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	})
+}
+
+func (k *jwk) hmacKey() (*Key, error) {
+	secret, err := base64.RawURLEncoding.DecodeString(k.K)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode secret: %v", service.ErrUnexpected, err)
+	}
+	return NewHMACKey(k.ID, secret)
+}
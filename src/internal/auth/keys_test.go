@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+// jwks encodes a json web key set with the given keys.
+func jwks(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatalf("encode jwks: %v", err)
+	}
+	return data
+}
+
+func TestParseJWKS(t *testing.T) {
+	public := &testRSAKeys()[0].PublicKey
+	b64 := base64.RawURLEncoding.EncodeToString
+	rsaJWK := map[string]string{
+		"kty": "RSA",
+		"kid": "r1",
+		"alg": RS256,
+		"use": "sig",
+		"n":   b64(public.N.Bytes()),
+		"e":   b64(big.NewInt(int64(public.E)).Bytes()),
+	}
+	octJWK := map[string]string{"kty": "oct", "kid": "h1", "k": b64(testSecret)}
+	with := func(jwk map[string]string, member, value string) map[string]string {
+		k := make(map[string]string, len(jwk))
+		for m, v := range jwk {
+			k[m] = v
+		}
+		k[member] = value
+		return k
+	}
+	small := new(big.Int).SetBit(new(big.Int), minRSABits-2, 1)
+
+	tests := map[string]struct {
+		data    []byte
+		wantIDs []string
+	}{
+		"Valid": {
+			data:    jwks(t, rsaJWK, octJWK),
+			wantIDs: []string{"r1", "h1"},
+		},
+		"SkipsUnusableKeys": {
+			data: jwks(t,
+				with(rsaJWK, "use", "enc"),
+				with(rsaJWK, "alg", "RS512"),
+				map[string]string{"kty": "EC", "kid": "e1", "crv": "P-256"},
+				octJWK,
+			),
+			wantIDs: []string{"h1"},
+		},
+		"Malformed":      {data: []byte(`{"keys": [`)},
+		"NotASet":        {data: []byte(`[]`)},
+		"Empty":          {data: jwks(t)},
+		"NoUsableKey":    {data: jwks(t, with(rsaJWK, "use", "enc"))},
+		"BadModulus":     {data: jwks(t, with(rsaJWK, "n", "not base64!"))},
+		"BadExponent":    {data: jwks(t, with(rsaJWK, "e", b64([]byte{1})))},
+		"HugeExponent":   {data: jwks(t, with(rsaJWK, "e", b64(big.NewInt(1<<40).Bytes())))},
+		"SmallRSAKey":    {data: jwks(t, with(rsaJWK, "n", b64(small.Bytes())))},
+		"BadSecret":      {data: jwks(t, with(octJWK, "k", "not base64!"))},
+		"ShortSecret":    {data: jwks(t, with(octJWK, "k", b64([]byte("short"))))},
+		"OneInvalidKey":  {data: jwks(t, octJWK, with(rsaJWK, "n", ""))},
+		"MissingMembers": {data: jwks(t, map[string]string{"kty": "RSA"})},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			keys, err := ParseJWKS(test.data)
+			if test.wantIDs == nil {
+				if !errors.Is(err, service.ErrUnexpected) {
+					t.Fatalf("want error %v, got %v and %d keys", service.ErrUnexpected, err, len(keys))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse jwks: %v", err)
+			}
+			if len(keys) != len(test.wantIDs) {
+				t.Fatalf("want keys %v, got %d keys", test.wantIDs, len(keys))
+			}
+			for i, id := range test.wantIDs {
+				if keys[i].ID != id {
+					t.Errorf("want key %d with id %q, got %q", i, id, keys[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestParseJWKSVerifies(t *testing.T) {
+	public := &testRSAKeys()[0].PublicKey
+	b64 := base64.RawURLEncoding.EncodeToString
+	keys, err := ParseJWKS(jwks(t, map[string]string{
+		"kty": "RSA",
+		"kid": "r1",
+		"n":   b64(public.N.Bytes()),
+		"e":   b64(big.NewInt(int64(public.E)).Bytes()),
+	}))
+	if err != nil {
+		t.Fatalf("parse jwks: %v", err)
+	}
+
+	v := NewVerifier(keys, &Config{})
+	if _, err := v.Verify(newToken(RS256, "r1").signedWith(t, testRSAKeys()[0])); err != nil {
+		t.Errorf("want the token signed with the key to be valid, got %v", err)
+	}
+	if _, err := v.Verify(newToken(RS256, "r1").signedWith(t, testRSAKeys()[1])); err == nil {
+		t.Error("want the token signed with another key to be invalid")
+	}
+}
+
+func TestParseRSAPublicKey(t *testing.T) {
+	public := &testRSAKeys()[0].PublicKey
+	pkix, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	encode := func(typ string, der []byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	}
+
+	tests := map[string]struct {
+		data    []byte
+		wantErr bool
+	}{
+		"PKIX":        {data: encode("PUBLIC KEY", pkix)},
+		"PKCS1":       {data: encode("RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(public))},
+		"NoPEM":       {data: []byte("not a pem block"), wantErr: true},
+		"Unsupported": {data: encode("CERTIFICATE", pkix), wantErr: true},
+		"Corrupt":     {data: encode("PUBLIC KEY", pkix[:10]), wantErr: true},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			key, err := ParseRSAPublicKey(test.data)
+			if test.wantErr {
+				if !errors.Is(err, service.ErrUnexpected) {
+					t.Fatalf("want error %v, got %v", service.ErrUnexpected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse public key: %v", err)
+			}
+			if key.Algorithm != RS256 || key.public.N.Cmp(public.N) != 0 {
+				t.Errorf("want the RS256 key, got %+v", key)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Config holds configuration variables for the [Verifier].
+type Config struct {
+	// Issuer is the expected "iss" claim of the tokens. It is not
+	// checked if empty.
+	Issuer string
+
+	// Audience must be listed in the "aud" claim of the tokens. It is
+	// not checked if empty.
+	Audience string
+
+	// AdminRole is the role, listed in the "roles" claim, which makes
+	// the caller an admin.
+	AdminRole string
+
+	// Leeway is the clock skew tolerated when checking the times at
+	// which the tokens become valid and expire.
+	Leeway time.Duration
+}
+
+// Verifier verifies bearer tokens, which are json web tokens (RFC 7519) signed
+// with one of its keys. A valid token must be signed with [HS256] or [RS256],
+// must not be expired, and must identify its subject.
+type Verifier struct {
+	keys []*Key
+	cfg  *Config
+}
+
+// NewVerifier creates a new [Verifier] instance, which trusts the given keys.
+func NewVerifier(keys []*Key, cfg *Config) *Verifier {
+	return &Verifier{
+		keys: keys,
+		cfg:  cfg,
+	}
+}
+
+// header is the decoded header of a token.
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// claims are the decoded claims of a token. The times are in seconds since the
+// epoch.
+type claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	Roles     []string `json:"roles"`
+}
+
+// audience is the "aud" claim, which is either a single string or an array of
+// strings.
+type audience []string
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	//nolint:wrapcheck // the error is wrapped by the caller
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+// Verify verifies the token and returns the principal that it identifies. This
+// function returns [ErrUnauthenticated] if the token is malformed, is not
+// signed by a trusted key, or its claims are not valid.
+func (v *Verifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrUnauthenticated)
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("%w: decode header: %v", ErrUnauthenticated, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode signature: %v", ErrUnauthenticated, err)
+	}
+	if !v.verifySignature(&h, parts[0]+"."+parts[1], sig) {
+		return nil, fmt.Errorf("%w: invalid signature", ErrUnauthenticated)
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, fmt.Errorf("%w: decode claims: %v", ErrUnauthenticated, err)
+	}
+	if err := v.checkClaims(&c); err != nil {
+		return nil, err
+	}
+	return &Principal{
+		Subject: c.Subject,
+		Admin:   v.cfg.AdminRole != "" && slices.Contains(c.Roles, v.cfg.AdminRole),
+	}, nil
+}
+
+// verifySignature returns true if the signature of the signed input is made
+// by one of the keys for the algorithm and the key id of the header.
+func (v *Verifier) verifySignature(h *header, signed string, sig []byte) bool {
+	digest := sha256.Sum256([]byte(signed))
+	for _, key := range v.keys {
+		if key.Algorithm != h.Algorithm || (key.ID != "" && h.KeyID != "" && key.ID != h.KeyID) {
+			continue
+		}
+		switch key.Algorithm {
+		case HS256:
+			mac := hmac.New(sha256.New, key.secret)
+			mac.Write([]byte(signed))
+			if hmac.Equal(mac.Sum(nil), sig) {
+				return true
+			}
+		case RS256:
+			if rsa.VerifyPKCS1v15(key.public, crypto.SHA256, digest[:], sig) == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkClaims returns [ErrUnauthenticated] if the claims are not valid at the
+// current time, or were not issued by and for the expected parties.
+func (v *Verifier) checkClaims(c *claims) error {
+	now := time.Now()
+	switch {
+	case c.Subject == "":
+		return fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	case c.ExpiresAt == nil:
+		return fmt.Errorf("%w: token has no expiration time", ErrUnauthenticated)
+	case !now.Before(epoch(*c.ExpiresAt).Add(v.cfg.Leeway)):
+		return fmt.Errorf("%w: token has expired", ErrUnauthenticated)
+	case c.NotBefore != nil && now.Add(v.cfg.Leeway).Before(epoch(*c.NotBefore)):
+		return fmt.Errorf("%w: token is not valid yet", ErrUnauthenticated)
+	case v.cfg.Issuer != "" && c.Issuer != v.cfg.Issuer:
+		return fmt.Errorf("%w: unexpected issuer %q", ErrUnauthenticated, c.Issuer)
+	case v.cfg.Audience != "" && !slices.Contains(c.Audience, v.cfg.Audience):
+		return fmt.Errorf("%w: token is not meant for %q", ErrUnauthenticated, v.cfg.Audience)
+	}
+	return nil
+}
+
+// decodeSegment decodes a base64url encoded json segment of a token.
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err //nolint:wrapcheck // the error is wrapped by the caller
+	}
+	return json.Unmarshal(data, v) //nolint:wrapcheck // the error is wrapped by the caller
+}
+
+// epoch converts a numeric date, in seconds since the epoch, to a time.
+func epoch(seconds float64) time.Time {
+	return time.UnixMilli(int64(seconds * 1000))
+}
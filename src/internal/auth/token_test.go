@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSecret is the shared secret of the HMAC key "h1".
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// testRSAKeys returns three RSA keys. The first two are trusted as "r1" and
+// "r2" by the verifier of [newTestVerifier], the third one is not.
+var testRSAKeys = sync.OnceValue(func() []*rsa.PrivateKey {
+	keys := make([]*rsa.PrivateKey, 3)
+	for i := range keys {
+		key, err := rsa.GenerateKey(rand.Reader, minRSABits)
+		if err != nil {
+			panic(err)
+		}
+		keys[i] = key
+	}
+	return keys
+})
+
+// newTestVerifier returns a verifier which trusts the HMAC key "h1" and the
+// RSA keys "r1" and "r2".
+func newTestVerifier(t *testing.T) *Verifier {
+	t.Helper()
+	h1, err := NewHMACKey("h1", testSecret)
+	if err != nil {
+		t.Fatalf("hmac key: %v", err)
+	}
+	keys := []*Key{h1}
+	for i, id := range []string{"r1", "r2"} {
+		key, err := NewRSAKey(id, &testRSAKeys()[i].PublicKey)
+		if err != nil {
+			t.Fatalf("rsa key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	return NewVerifier(keys, &Config{
+		Issuer:    "https://issuer.example",
+		Audience:  "booking-service",
+		AdminRole: "admin",
+		Leeway:    30 * time.Second,
+	})
+}
+
+// token holds the parts of a token before it is signed.
+type token struct {
+	alg    string
+	kid    string
+	claims map[string]any
+}
+
+// newToken returns a token for the user "u1" with valid claims, which is
+// signed with the given algorithm.
+func newToken(alg, kid string) *token {
+	now := time.Now()
+	return &token{
+		alg: alg,
+		kid: kid,
+		claims: map[string]any{
+			"iss": "https://issuer.example",
+			"sub": "u1",
+			"aud": "booking-service",
+			"exp": now.Add(time.Hour).Unix(),
+			"nbf": now.Add(-time.Minute).Unix(),
+		},
+	}
+}
+
+// with sets the claim to the given value, or removes it if the value is nil.
+func (tok *token) with(claim string, value any) *token {
+	if value == nil {
+		delete(tok.claims, claim)
+	} else {
+		tok.claims[claim] = value
+	}
+	return tok
+}
+
+// signedWith signs the token with the HMAC secret or the RSA private key.
+// Tokens with the "none" algorithm are not signed.
+func (tok *token) signedWith(t *testing.T, key any) string {
+	t.Helper()
+	h := map[string]string{"alg": tok.alg}
+	if tok.kid != "" {
+		h["kid"] = tok.kid
+	}
+	signed := encodeSegment(t, h) + "." + encodeSegment(t, tok.claims)
+
+	var sig []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatalf("sign token: %v", err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("encode segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// tamper replaces the claims of the signed token, keeping its signature.
+func tamper(t *testing.T, signed string, claims map[string]any) string {
+	t.Helper()
+	parts := strings.Split(signed, ".")
+	parts[1] = encodeSegment(t, claims)
+	return strings.Join(parts, ".")
+}
+
+func TestVerify(t *testing.T) {
+	rsaKeys := testRSAKeys()
+	now := time.Now()
+
+	// The public key of "r1", which an attacker might use as an HMAC
+	// secret in order to forge a HS256 token.
+	der, err := x509.MarshalPKIXPublicKey(&rsaKeys[0].PublicKey)
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	tests := map[string]struct {
+		token     string
+		wantErr   bool
+		wantAdmin bool
+	}{
+		"HMAC": {
+			token: newToken(HS256, "h1").signedWith(t, testSecret),
+		},
+		"RSA": {
+			token: newToken(RS256, "r1").signedWith(t, rsaKeys[0]),
+		},
+		"Admin": {
+			token:     newToken(RS256, "r1").with("roles", []string{"admin"}).signedWith(t, rsaKeys[0]),
+			wantAdmin: true,
+		},
+		"OtherRole": {
+			token: newToken(RS256, "r1").with("roles", []string{"user"}).signedWith(t, rsaKeys[0]),
+		},
+		"Malformed": {
+			token:   "header.claims",
+			wantErr: true,
+		},
+		"BadSignature": {
+			token:   newToken(HS256, "h1").signedWith(t, []byte("another secret of thirty-two bytes")),
+			wantErr: true,
+		},
+		"TamperedPayload": {
+			token: tamper(t, newToken(RS256, "r1").signedWith(t, rsaKeys[0]),
+				newToken(RS256, "r1").with("roles", []string{"admin"}).claims),
+			wantErr: true,
+		},
+		"AlgNone": {
+			token:   newToken("none", "").signedWith(t, nil),
+			wantErr: true,
+		},
+		"AlgNoneWithKeyID": {
+			token:   newToken("none", "h1").signedWith(t, nil),
+			wantErr: true,
+		},
+		"HMACWithRSAPublicKey": {
+			token:   newToken(HS256, "r1").signedWith(t, publicPEM),
+			wantErr: true,
+		},
+		"RSAWithHMACKeyID": {
+			token:   newToken(RS256, "h1").signedWith(t, rsaKeys[0]),
+			wantErr: true,
+		},
+		"Expired": {
+			token: newToken(HS256, "h1").with("exp", now.Add(-time.Minute).Unix()).
+				signedWith(t, testSecret),
+			wantErr: true,
+		},
+		"ExpiredWithinLeeway": {
+			token: newToken(HS256, "h1").with("exp", now.Add(-10*time.Second).Unix()).
+				signedWith(t, testSecret),
+		},
+		"NoExpiration": {
+			token:   newToken(HS256, "h1").with("exp", nil).signedWith(t, testSecret),
+			wantErr: true,
+		},
+		"NotYetValid": {
+			token: newToken(HS256, "h1").with("nbf", now.Add(time.Minute).Unix()).
+				signedWith(t, testSecret),
+			wantErr: true,
+		},
+		"NotBeforeWithinLeeway": {
+			token: newToken(HS256, "h1").with("nbf", now.Add(10*time.Second).Unix()).
+				signedWith(t, testSecret),
+		},
+		"NoSubject": {
+			token:   newToken(HS256, "h1").with("sub", nil).signedWith(t, testSecret),
+			wantErr: true,
+		},
+		"WrongIssuer": {
+			token:   newToken(HS256, "h1").with("iss", "https://evil.example").signedWith(t, testSecret),
+			wantErr: true,
+		},
+		"WrongAudience": {
+			token:   newToken(HS256, "h1").with("aud", "other-service").signedWith(t, testSecret),
+			wantErr: true,
+		},
+		"AudienceList": {
+			token: newToken(HS256, "h1").
+				with("aud", []string{"other-service", "booking-service"}).signedWith(t, testSecret),
+		},
+		"UnknownKeyID": {
+			token:   newToken(RS256, "r9").signedWith(t, rsaKeys[0]),
+			wantErr: true,
+		},
+		"MismatchedKeyID": {
+			token:   newToken(RS256, "r2").signedWith(t, rsaKeys[0]),
+			wantErr: true,
+		},
+		"NoKeyID": {
+			token: newToken(RS256, "").signedWith(t, rsaKeys[1]),
+		},
+		"NoKeyIDUntrustedKey": {
+			token:   newToken(RS256, "").signedWith(t, rsaKeys[2]),
+			wantErr: true,
+		},
+	}
+	v := newTestVerifier(t)
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			p, err := v.Verify(test.token)
+			if test.wantErr {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Fatalf("want error %v, got %v and %+v", ErrUnauthenticated, err, p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if p.Subject != "u1" || p.Admin != test.wantAdmin {
+				t.Errorf("want subject %q with admin %t, got %+v", "u1", test.wantAdmin, p)
+			}
+		})
+	}
+}
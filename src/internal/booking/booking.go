@@ -0,0 +1,184 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/auth"
+	"github.com/eventscompass/booking-service/src/internal/outbox"
+	"github.com/eventscompass/booking-service/src/internal/validation"
+	"github.com/eventscompass/service-framework/pubsub"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// Manager implements the business logic for managing bookings. It sits between
+// the transport layer (e.g. the rest api) and the database layer, and makes
+// sure that only valid bookings end up in the container.
+type Manager struct {
+	// bookingsDB is used to read and store bookings in a container database.
+	bookingsDB BookingsContainer
+
+	// relay delivers the messages that the manager writes to the
+	// outbox, in order to notify other services about changes to
+	// the bookings.
+	relay *outbox.Relay
+
+	// cfg is used to configure the manager.
+	cfg *Config
+
+	// watchers are notified about the bookings that change.
+	watchers watchers
+}
+
+// Config holds configuration variables for the [Manager].
+type Config struct {
+	// HoldTTL is the time for which a seat hold is valid. A hold
+	// that is not confirmed within this time expires.
+	HoldTTL time.Duration
+}
+
+// NewManager creates a new [Manager] instance.
+func NewManager(bookingsDB BookingsContainer, relay *outbox.Relay, cfg *Config) *Manager {
+	return &Manager{
+		bookingsDB: bookingsDB,
+		relay:      relay,
+		cfg:        cfg,
+	}
+}
+
+// Create creates a new booking. The booking must be made by a known user for a
+// known event. An [pubsub.EventBooked] message is stored in the outbox in the
+// same transaction as the booking, and is later published by the relay. This
+// function returns a [*validation.Error] listing every violation if the
+// booking is missing required fields, sets fields managed by the service,
+// references a user or an event that does not exist, or has a date outside of
+// the event. This function returns [service.ErrSpaceFull] if the event is sold
+// out. This function returns [service.ErrNotAllowed] if the event has already
+// started or was cancelled, or if the caller books for another user.
+func (m *Manager) Create(ctx context.Context, b *Booking) error {
+	if err := validateBooking(b); err != nil {
+		return fmt.Errorf("create booking: %w", err)
+	}
+	if err := auth.Authorize(ctx, b.UserID); err != nil {
+		return fmt.Errorf("create booking: %w", err)
+	}
+
+	// A seat is reserved right away, so the booking is confirmed.
+	b.Status = StatusConfirmed
+	b.ExpiresAt = nil
+
+	msg, err := outbox.NewMessage(pubsub.EventBookedTopic, pubsub.EventBooked{
+		EventID: b.EventID,
+		UserID:  b.UserID,
+	})
+	if err != nil {
+		return fmt.Errorf("event booked message: %w", err)
+	}
+
+	// Store the booking together with the message, so that other services
+	// are notified if and only if the booking is created.
+	if err := m.insert(ctx, b, msg); err != nil {
+		return fmt.Errorf("create booking: %w", err)
+	}
+	return nil
+}
+
+// insert validates the references of the booking, reserves a seat for it and
+// stores it together with the given outbox messages in a single transaction.
+// The id of the booking is set by the manager, and so is its date, unless it
+// was chosen by the client.
+func (m *Manager) insert(ctx context.Context, b *Booking, msgs ...*OutboxMessage) error {
+	// Only a date chosen by the client has to fall within the event. The
+	// default date is the time of booking, which precedes the event.
+	chosen := !b.Date.IsZero()
+	if err := stamp(b); err != nil {
+		return err
+	}
+
+	// Make sure that the booking references existing entities, and that its
+	// date falls within the event.
+	var v validation.Validator
+	event, err := m.GetEvent(ctx, b.EventID)
+	if err := checkReference(&v, "event_id", err); err != nil {
+		return err
+	}
+	_, err = m.getUser(ctx, b.UserID)
+	if err := checkReference(&v, "user_id", err); err != nil {
+		return err
+	}
+	if event != nil && chosen {
+		checkDate(&v, b.Date, event)
+	}
+	if err := v.Err(); err != nil {
+		return err //nolint:wrapcheck // the error lists the violations
+	}
+
+	// Seats can be booked only until the event starts. Events whose start
+	// time is not known can always be booked.
+	if !event.Start.IsZero() && !time.Now().Before(event.Start) {
+		return fmt.Errorf("%w: event %q has already started", service.ErrNotAllowed, b.EventID)
+	}
+
+	err = m.bookingsDB.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := m.bookingsDB.ReserveSeats(ctx, b.EventID, 1); err != nil {
+			return fmt.Errorf("reserve seat: %w", err)
+		}
+		if err := m.bookingsDB.Create(ctx, BookingsCollection, *b); err != nil {
+			return fmt.Errorf("insert booking: %w", err)
+		}
+		for _, msg := range msgs {
+			if err := m.bookingsDB.Create(ctx, OutboxCollection, *msg); err != nil {
+				return fmt.Errorf("insert message: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("transaction: %w", err)
+	}
+	if len(msgs) > 0 {
+		m.relay.Notify()
+	}
+	m.watchers.notify(b.ID)
+	return nil
+}
+
+// Get retrieves the booking with the given id. This function returns
+// [service.ErrNotFound] if the booking does not exist. This function returns
+// [service.ErrNotAllowed] if the booking belongs to another user than the
+// caller.
+func (m *Manager) Get(ctx context.Context, id string) (*Booking, error) {
+	elem, err := m.bookingsDB.GetByID(ctx, BookingsCollection, id)
+	if err != nil {
+		return nil, fmt.Errorf("get booking: %w", err)
+	}
+	b, ok := elem.(Booking)
+	if !ok {
+		return nil, service.Unexpected(ctx, fmt.Errorf("invalid booking type %T", elem))
+	}
+	if err := auth.Authorize(ctx, b.UserID); err != nil {
+		return nil, fmt.Errorf("get booking %q: %w", id, err)
+	}
+	return &b, nil
+}
+
+// stamp assigns a new id to the booking, and sets its creation and update
+// times to the current time. The date of the booking defaults to the current
+// time as well.
+func stamp(b *Booking) error {
+	id, err := NewID()
+	if err != nil {
+		return fmt.Errorf("booking id: %w", err)
+	}
+	now := time.Now().UTC()
+	b.ID = id
+	if b.Date.IsZero() {
+		b.Date = now
+	}
+	b.Date = b.Date.UTC()
+	b.CreatedAt = now
+	b.UpdatedAt = now
+	return nil
+}
@@ -0,0 +1,122 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/memory"
+	"github.com/eventscompass/booking-service/src/internal/outbox"
+	"github.com/eventscompass/booking-service/src/internal/validation"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// newTestManager returns a manager backed by an in-memory container, which
+// holds the user "u1" and the event "e1". The event starts in an hour.
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	db := memory.NewMemoryContainer()
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	start := time.Now().Add(time.Hour).UTC()
+	if err := db.Create(ctx, UsersCollection, User{ID: "u1", Name: "Alice"}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	event := Event{ID: "e1", Start: start, End: start.Add(2 * time.Hour), Capacity: 10}
+	if err := db.Create(ctx, EventsCollection, event); err != nil {
+		t.Fatalf("create event: %v", err)
+	}
+
+	relay := outbox.NewRelay(db, memory.NewBus(), &outbox.Config{
+		Interval:       time.Minute,
+		Retention:      time.Hour,
+		MaxAttempts:    3,
+		PublishTimeout: time.Second,
+	})
+	return NewManager(db, relay, &Config{HoldTTL: time.Minute})
+}
+
+func TestCreateWithoutDate(t *testing.T) {
+	m := newTestManager(t)
+
+	before := time.Now()
+	b := &Booking{UserID: "u1", EventID: "e1"}
+	if err := m.Create(context.Background(), b); err != nil {
+		t.Fatalf("create booking: %v", err)
+	}
+	if b.Status != StatusConfirmed {
+		t.Errorf("want status %q, got %q", StatusConfirmed, b.Status)
+	}
+	if b.Date.Before(before.Truncate(time.Millisecond)) {
+		t.Errorf("want the date to default to the time of booking, got %v", b.Date)
+	}
+}
+
+func TestHoldWithoutDate(t *testing.T) {
+	m := newTestManager(t)
+
+	b := &Booking{UserID: "u1", EventID: "e1"}
+	if err := m.Hold(context.Background(), b); err != nil {
+		t.Fatalf("hold booking: %v", err)
+	}
+	if b.Status != StatusPending {
+		t.Errorf("want status %q, got %q", StatusPending, b.Status)
+	}
+}
+
+func TestCreateWithDateOutsideEvent(t *testing.T) {
+	m := newTestManager(t)
+
+	b := &Booking{UserID: "u1", EventID: "e1", Date: time.Now()}
+	err := m.Create(context.Background(), b)
+	var verr *validation.Error
+	if !errors.As(err, &verr) {
+		t.Fatalf("want a validation error, got %v", err)
+	}
+	if len(verr.Violations) != 1 || verr.Violations[0].Field != "date" {
+		t.Fatalf("want a violation of the date, got %+v", verr.Violations)
+	}
+}
+
+func TestCreateWithUnknownReference(t *testing.T) {
+	tests := map[string]struct {
+		userID, eventID string
+		wantFields      []string
+	}{
+		"UnknownUser":  {userID: "u9", eventID: "e1", wantFields: []string{"user_id"}},
+		"UnknownEvent": {userID: "u1", eventID: "e9", wantFields: []string{"event_id"}},
+		"Both":         {userID: "u9", eventID: "e9", wantFields: []string{"event_id", "user_id"}},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			m := newTestManager(t)
+
+			b := &Booking{UserID: test.userID, EventID: test.eventID}
+			err := m.Create(context.Background(), b)
+			if !errors.Is(err, validation.ErrInvalid) || !errors.Is(err, service.ErrBadRequest) {
+				t.Fatalf("want an invalid request, got %v", err)
+			}
+			if errors.Is(err, service.ErrNotFound) {
+				t.Errorf("want the missing entity not to be reported as not found, got %v", err)
+			}
+			var verr *validation.Error
+			if !errors.As(err, &verr) || len(verr.Violations) != len(test.wantFields) {
+				t.Fatalf("want violations of %v, got %v", test.wantFields, err)
+			}
+			for i, field := range test.wantFields {
+				if verr.Violations[i].Field != field {
+					t.Errorf("want violation %d of %q, got %q", i, field, verr.Violations[i].Field)
+				}
+			}
+
+			list, err := m.bookingsDB.ListBookings(context.Background(), &BookingFilter{Limit: 10})
+			if err != nil || len(list) != 0 {
+				t.Errorf("want no stored bookings, got %+v and %v", list, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// SetEventCapacity sets the maximum number of seats that can be booked for
+// the event with the given id. A zero capacity means that the number of
+// bookings is not limited. Lowering the capacity below the number of already
+// booked seats does not cancel any bookings, but no new bookings are accepted.
+// This function returns [service.ErrBadRequest] if the capacity is negative.
+// This function returns [service.ErrNotFound] if the event does not exist.
+func (m *Manager) SetEventCapacity(ctx context.Context, id string, capacity int) error {
+	return m.setCapacity(ctx, EventsCollection, id, capacity)
+}
+
+// SetLocationCapacity sets the default capacity of the events hosted at the
+// location with the given id. The capacity is applied to events created after
+// the change. This function returns [service.ErrBadRequest] if the capacity is
+// negative. This function returns [service.ErrNotFound] if the location does
+// not exist.
+func (m *Manager) SetLocationCapacity(ctx context.Context, id string, capacity int) error {
+	return m.setCapacity(ctx, LocationsCollection, id, capacity)
+}
+
+func (m *Manager) setCapacity(ctx context.Context, collection, id string, capacity int) error {
+	if capacity < 0 {
+		return fmt.Errorf("%w: negative capacity %d", service.ErrBadRequest, capacity)
+	}
+	if err := m.bookingsDB.SetCapacity(ctx, collection, id, capacity); err != nil {
+		return fmt.Errorf("set capacity: %w", err)
+	}
+	return nil
+}
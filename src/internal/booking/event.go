@@ -0,0 +1,121 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// cascadeBatchSize is the number of bookings processed at once when the
+// bookings of a cancelled event are cancelled.
+const cascadeBatchSize = 100
+
+// GetEvent retrieves the event with the given id. This function returns
+// [service.ErrNotFound] if the event does not exist.
+func (m *Manager) GetEvent(ctx context.Context, id string) (*Event, error) {
+	elem, err := m.bookingsDB.GetByID(ctx, EventsCollection, id)
+	if err != nil {
+		return nil, fmt.Errorf("get event: %w", err)
+	}
+	e, ok := elem.(Event)
+	if !ok {
+		return nil, service.Unexpected(ctx, fmt.Errorf("invalid event type %T", elem))
+	}
+	return &e, nil
+}
+
+// GetLocation retrieves the location with the given id. This function returns
+// [service.ErrNotFound] if the location does not exist.
+func (m *Manager) GetLocation(ctx context.Context, id string) (*Location, error) {
+	elem, err := m.bookingsDB.GetByID(ctx, LocationsCollection, id)
+	if err != nil {
+		return nil, fmt.Errorf("get location: %w", err)
+	}
+	l, ok := elem.(Location)
+	if !ok {
+		return nil, service.Unexpected(ctx, fmt.Errorf("invalid location type %T", elem))
+	}
+	return &l, nil
+}
+
+// getUser retrieves the user with the given id. This function returns
+// [service.ErrNotFound] if the user does not exist.
+func (m *Manager) getUser(ctx context.Context, id string) (*User, error) {
+	elem, err := m.bookingsDB.GetByID(ctx, UsersCollection, id)
+	if err != nil {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	u, ok := elem.(User)
+	if !ok {
+		return nil, service.Unexpected(ctx, fmt.Errorf("invalid user type %T", elem))
+	}
+	return &u, nil
+}
+
+// CancelEvent marks the event with the given id as cancelled and cancels all of
+// its bookings, in batches. A [BookingCancelled] message is stored in the
+// outbox for every cancelled booking. The waitlist of the event is removed.
+// Calling this function again for the same event is a no-op, except that it
+// continues the cascade if a previous call was interrupted.
+func (m *Manager) CancelEvent(ctx context.Context, eventID string) error {
+	if err := m.bookingsDB.CancelEvent(ctx, eventID); err != nil {
+		return fmt.Errorf("cancel event: %w", err)
+	}
+
+	f := &BookingFilter{EventID: eventID, Limit: cascadeBatchSize}
+	for {
+		bookings, err := m.bookingsDB.ListBookings(ctx, f)
+		if err != nil {
+			return fmt.Errorf("list bookings: %w", err)
+		}
+		if len(bookings) == 0 {
+			break
+		}
+		if err := m.cancelAll(ctx, bookings); err != nil {
+			return err
+		}
+		last := bookings[len(bookings)-1]
+		f.AfterDate, f.AfterID = last.Date, last.ID
+	}
+
+	entries, err := m.bookingsDB.Waitlist(ctx, eventID)
+	if err != nil {
+		return fmt.Errorf("get waitlist: %w", err)
+	}
+	for _, e := range entries {
+		err := m.bookingsDB.Delete(ctx, WaitlistCollection, e.ID)
+		if err != nil && !errors.Is(err, service.ErrNotFound) {
+			return fmt.Errorf("delete waitlist entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// cancelAll cancels the given bookings of a cancelled event. Bookings that
+// cannot be cancelled in their status, e.g. because they already are, are
+// skipped.
+func (m *Manager) cancelAll(ctx context.Context, bookings []Booking) error {
+	defer m.relay.Notify()
+
+	for i := range bookings {
+		b := &bookings[i]
+		if checkTransition(b.Status, StatusCancelled) != nil {
+			continue
+		}
+		err := m.cancel(ctx, b, "event cancelled")
+		if errors.Is(err, service.ErrNotAllowed) {
+			// The booking was concurrently changed, e.g. cancelled by
+			// its user or expired.
+			slog.Info("skipping changed booking", slog.String("id", b.ID))
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cancel booking %q: %w", b.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/auth"
+	"github.com/eventscompass/booking-service/src/internal/outbox"
+	"github.com/eventscompass/service-framework/pubsub"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// Hold reserves a seat for the user while the checkout is in progress. A hold
+// is a pending booking, which counts against the capacity of the event, and
+// which expires unless it is confirmed within the configured TTL. This
+// function returns a [*validation.Error] under the same conditions as
+// [Manager.Create]. This function returns [service.ErrSpaceFull] if the event
+// is sold out. This function returns [service.ErrNotAllowed] if the event has
+// already started or was cancelled, or if the caller holds a seat for another
+// user.
+func (m *Manager) Hold(ctx context.Context, b *Booking) error {
+	if err := validateBooking(b); err != nil {
+		return fmt.Errorf("create hold: %w", err)
+	}
+	if err := auth.Authorize(ctx, b.UserID); err != nil {
+		return fmt.Errorf("create hold: %w", err)
+	}
+
+	expiresAt := time.Now().UTC().Add(m.cfg.HoldTTL)
+	b.Status = StatusPending
+	b.ExpiresAt = &expiresAt
+
+	if err := m.insert(ctx, b); err != nil {
+		return fmt.Errorf("create hold: %w", err)
+	}
+	return nil
+}
+
+// Confirm turns the hold with the given id into a confirmed booking. An
+// [pubsub.EventBooked] message is stored in the outbox in the same
+// transaction. This function returns [service.ErrNotFound] if the hold does
+// not exist. This function returns [service.ErrNotAllowed] if the hold has
+// expired or was already confirmed or cancelled, or if it belongs to another
+// user than the caller.
+func (m *Manager) Confirm(ctx context.Context, id string) error {
+	b, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := checkTransition(b.Status, StatusConfirmed); err != nil {
+		return err
+	}
+	if b.ExpiresAt != nil && b.ExpiresAt.Before(time.Now()) {
+		return fmt.Errorf("%w: hold %q has expired", service.ErrNotAllowed, id)
+	}
+
+	msg, err := outbox.NewMessage(pubsub.EventBookedTopic, pubsub.EventBooked{
+		EventID: b.EventID,
+		UserID:  b.UserID,
+	})
+	if err != nil {
+		return fmt.Errorf("event booked message: %w", err)
+	}
+
+	// The seat was reserved when the hold was created. The status update
+	// fails if the reaper concurrently expired the hold.
+	err = m.bookingsDB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := m.bookingsDB.UpdateStatus(ctx, b.ID, StatusPending, StatusConfirmed)
+		if err != nil {
+			return fmt.Errorf("update status: %w", err)
+		}
+		if err := m.bookingsDB.Create(ctx, OutboxCollection, *msg); err != nil {
+			return fmt.Errorf("insert message: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("confirm hold: %w", err)
+	}
+	m.relay.Notify()
+	m.watchers.notify(b.ID)
+	return nil
+}
+
+// RunReaper expires the holds that were not confirmed in time, until the
+// context is cancelled. The expired holds are checked every interval. This is
+// a blocking function.
+func (m *Manager) RunReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.expireHolds(ctx); err != nil {
+			slog.Warn("failed to expire holds", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// reaperBatchSize is the maximum number of holds that are expired at once.
+const reaperBatchSize = 100
+
+// expireHolds expires all the holds that were not confirmed in time. The seat
+// of every expired hold is given back to the event and a [HoldExpired]
+// message is stored in the outbox. The freed seat is held for the first user
+// in the waitlist of the event, if any.
+func (m *Manager) expireHolds(ctx context.Context) error {
+	for {
+		holds, err := m.bookingsDB.ExpiredHolds(ctx, time.Now().UTC(), reaperBatchSize)
+		if err != nil {
+			return fmt.Errorf("expired holds: %w", err)
+		}
+
+		for i := range holds {
+			err := m.expire(ctx, &holds[i])
+			if errors.Is(err, service.ErrNotAllowed) {
+				continue // the hold was concurrently confirmed or cancelled
+			}
+			if err != nil {
+				return fmt.Errorf("expire hold %q: %w", holds[i].ID, err)
+			}
+		}
+		if len(holds) > 0 {
+			m.relay.Notify()
+		}
+
+		if len(holds) < reaperBatchSize {
+			return nil
+		}
+	}
+}
+
+// expire expires a single hold.
+func (m *Manager) expire(ctx context.Context, hold *Booking) error {
+	msg, err := outbox.NewMessage(HoldExpiredTopic, HoldExpired{
+		HoldID:  hold.ID,
+		EventID: hold.EventID,
+		UserID:  hold.UserID,
+	})
+	if err != nil {
+		return fmt.Errorf("hold expired message: %w", err)
+	}
+
+	var promoted string
+	err = m.bookingsDB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := m.bookingsDB.UpdateStatus(ctx, hold.ID, StatusPending, StatusExpired)
+		if err != nil {
+			return fmt.Errorf("update status: %w", err)
+		}
+		if err := m.bookingsDB.ReleaseSeats(ctx, hold.EventID, 1); err != nil {
+			return fmt.Errorf("release seat: %w", err)
+		}
+		if err := m.bookingsDB.Create(ctx, OutboxCollection, *msg); err != nil {
+			return fmt.Errorf("insert message: %w", err)
+		}
+		if promoted, err = m.promote(ctx, hold.EventID); err != nil {
+			return fmt.Errorf("promote from waitlist: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("transaction: %w", err)
+	}
+	m.watchers.notify(hold.ID, promoted)
+	return nil
+}
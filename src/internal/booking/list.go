@@ -0,0 +1,152 @@
+package booking
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/auth"
+	"github.com/eventscompass/service-framework/service"
+)
+
+const (
+	// defaultPageSize is the number of bookings listed in a page, if
+	// the client did not request a specific number.
+	defaultPageSize = 20
+
+	// maxPageSize is the maximum number of bookings listed in a page.
+	maxPageSize = 100
+)
+
+// ListQuery selects the bookings to be listed. Empty fields are not used for
+// filtering.
+type ListQuery struct {
+	UserID  string
+	EventID string
+	Status  BookingStatus
+
+	// From and To select the bookings whose date falls in the
+	// interval [From, To).
+	From time.Time
+	To   time.Time
+
+	// Cursor is the opaque cursor returned with the previous page.
+	// An empty cursor selects the first page.
+	Cursor string
+
+	// Limit is the maximum number of bookings in the page. Zero
+	// selects the default page size.
+	Limit int
+}
+
+// Page is a page of listed bookings.
+type Page struct {
+	Bookings []Booking `json:"bookings"`
+
+	// NextCursor is the cursor for retrieving the next page. It is
+	// empty if this is the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursor is the position of the last booking of a page in the listing order.
+// The position is stable, i.e. bookings created in the meantime do not cause
+// bookings to be skipped or listed twice.
+type cursor struct {
+	Date time.Time `json:"d"`
+	ID   string    `json:"id"`
+}
+
+// List lists the bookings that match the query, ordered by their date. Callers
+// who are not admins list only their own bookings. This function returns
+// [service.ErrBadRequest] if the query is not valid. This function returns
+// [service.ErrNotAllowed] if the query selects the bookings of another user
+// than the caller.
+func (m *Manager) List(ctx context.Context, q *ListQuery) (*Page, error) {
+	if p, ok := auth.FromContext(ctx); ok && !p.Admin && q.UserID == "" {
+		own := *q
+		own.UserID = p.Subject
+		q = &own
+	}
+	if err := auth.Authorize(ctx, q.UserID); err != nil {
+		return nil, fmt.Errorf("list bookings: %w", err)
+	}
+
+	f, err := q.filter()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one more booking to find out whether there is a next page.
+	limit := f.Limit
+	f.Limit++
+	bookings, err := m.bookingsDB.ListBookings(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("list bookings: %w", err)
+	}
+
+	page := &Page{Bookings: bookings}
+	if len(bookings) > limit {
+		page.Bookings = bookings[:limit]
+		last := page.Bookings[limit-1]
+		page.NextCursor = encodeCursor(&cursor{Date: last.Date, ID: last.ID})
+	}
+	if page.Bookings == nil {
+		page.Bookings = []Booking{}
+	}
+	return page, nil
+}
+
+// filter validates the query and converts it to a [BookingFilter].
+func (q *ListQuery) filter() (*BookingFilter, error) {
+	if q.Status != "" && !knownStatus(q.Status) {
+		return nil, fmt.Errorf("%w: unknown status %q", service.ErrBadRequest, q.Status)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return nil, fmt.Errorf("%w: from must be before to", service.ErrBadRequest)
+	}
+	if q.Limit < 0 || q.Limit > maxPageSize {
+		return nil, fmt.Errorf(
+			"%w: limit must be between 1 and %d", service.ErrBadRequest, maxPageSize)
+	}
+
+	f := &BookingFilter{
+		UserID:  q.UserID,
+		EventID: q.EventID,
+		Status:  q.Status,
+		From:    q.From,
+		To:      q.To,
+		Limit:   q.Limit,
+	}
+	if f.Limit == 0 {
+		f.Limit = defaultPageSize
+	}
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		f.AfterDate, f.AfterID = c.Date, c.ID
+	}
+	return f, nil
+}
+
+func encodeCursor(c *cursor) string {
+	data, _ := json.Marshal(c) //nolint:errcheck // marshaling the cursor cannot fail
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns [service.ErrBadRequest] if the cursor is malformed.
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode cursor: %v", service.ErrBadRequest, err)
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", service.ErrBadRequest)
+	}
+	return &c, nil
+}
@@ -0,0 +1,103 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/outbox"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// transitions lists for every booking status the statuses that a booking can
+// move to. Statuses that are not listed are final.
+var transitions = map[BookingStatus][]BookingStatus{
+	StatusPending:   {StatusConfirmed, StatusCancelled, StatusExpired},
+	StatusConfirmed: {StatusCancelled, StatusCheckedIn},
+}
+
+// knownStatus returns true if the status is one of the booking statuses.
+func knownStatus(status BookingStatus) bool {
+	switch status {
+	case StatusPending, StatusConfirmed, StatusCancelled, StatusCheckedIn, StatusExpired:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkTransition returns [service.ErrNotAllowed] if a booking cannot move from
+// one status to the other.
+func checkTransition(from, to BookingStatus) error {
+	for _, s := range transitions[from] {
+		if s == to {
+			return nil
+		}
+	}
+	return fmt.Errorf(
+		"%w: booking cannot move from %q to %q", service.ErrNotAllowed, from, to)
+}
+
+// Cancel cancels the booking with the given id and gives its seat back to the
+// event. A [BookingCancelled] message is stored in the outbox in the same
+// transaction. The freed seat is held for the first user in the waitlist of
+// the event, if any. This function returns [service.ErrNotFound] if the booking does
+// not exist. This function returns [service.ErrNotAllowed] if the booking
+// cannot be cancelled in its current status, or if it belongs to another user
+// than the caller.
+func (m *Manager) Cancel(ctx context.Context, id string) error {
+	b, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := checkTransition(b.Status, StatusCancelled); err != nil {
+		return err
+	}
+	if err := m.cancel(ctx, b, ""); err != nil {
+		return fmt.Errorf("cancel booking: %w", err)
+	}
+	m.relay.Notify()
+	return nil
+}
+
+// cancel cancels the given booking and stores a [BookingCancelled] message
+// with the given reason in the outbox, in a single transaction. The freed seat
+// is held for the first user in the waitlist of the event, if any. This
+// function returns [service.ErrNotAllowed] if the status of the booking was
+// concurrently changed. The caller is responsible for notifying the relay.
+func (m *Manager) cancel(ctx context.Context, b *Booking, reason string) error {
+	msg, err := outbox.NewMessage(BookingCancelledTopic, BookingCancelled{
+		BookingID: b.ID,
+		EventID:   b.EventID,
+		UserID:    b.UserID,
+		Reason:    reason,
+	})
+	if err != nil {
+		return fmt.Errorf("booking cancelled message: %w", err)
+	}
+
+	// The status update fails if the booking was concurrently changed, thus
+	// the seat is released at most once.
+	var promoted string
+	err = m.bookingsDB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := m.bookingsDB.UpdateStatus(ctx, b.ID, b.Status, StatusCancelled)
+		if err != nil {
+			return fmt.Errorf("update status: %w", err)
+		}
+		if err := m.bookingsDB.ReleaseSeats(ctx, b.EventID, 1); err != nil {
+			return fmt.Errorf("release seat: %w", err)
+		}
+		if err := m.bookingsDB.Create(ctx, OutboxCollection, *msg); err != nil {
+			return fmt.Errorf("insert message: %w", err)
+		}
+		if promoted, err = m.promote(ctx, b.EventID); err != nil {
+			return fmt.Errorf("promote from waitlist: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("transaction: %w", err)
+	}
+	m.watchers.notify(b.ID, promoted)
+	return nil
+}
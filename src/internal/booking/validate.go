@@ -0,0 +1,83 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/validation"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// The validation rules of the requests are enforced by the [Manager], so that
+// they hold for every transport. The rules that only concern the submitted
+// fields are checked first, and the rules that concern the referenced entities
+// are checked once those are retrieved.
+
+// maxIDLen is the maximum length of the ids of the entities referenced by a
+// request.
+const maxIDLen = 128
+
+// validateBooking checks the fields of a booking or a hold submitted by a
+// client. The fields that are managed by the service must not be set.
+func validateBooking(b *Booking) error {
+	var v validation.Validator
+	checkID(&v, "user_id", b.UserID)
+	checkID(&v, "event_id", b.EventID)
+	v.Check(b.ID == "", "id", "is assigned by the service")
+	v.Check(b.Status == "", "status", "is assigned by the service")
+	v.Check(b.ExpiresAt == nil, "expires_at", "is assigned by the service")
+	v.Check(b.CreatedAt.IsZero(), "created_at", "is assigned by the service")
+	v.Check(b.UpdatedAt.IsZero(), "updated_at", "is assigned by the service")
+	return v.Err() //nolint:wrapcheck // the error lists the violations
+}
+
+// validateEntry checks the fields of a waitlist entry submitted by a client.
+// The fields that are managed by the service must not be set.
+func validateEntry(e *WaitlistEntry) error {
+	var v validation.Validator
+	checkID(&v, "user_id", e.UserID)
+	checkID(&v, "event_id", e.EventID)
+	v.Check(e.ID == "", "id", "is assigned by the service")
+	v.Check(e.JoinedAt.IsZero(), "joined_at", "is assigned by the service")
+	return v.Err() //nolint:wrapcheck // the error lists the violations
+}
+
+// checkID checks that the id of a referenced entity is set and not too long.
+func checkID(v *validation.Validator, field, id string) {
+	if v.Check(id != "", field, "is required") {
+		v.Check(len(id) <= maxIDLen, field, fmt.Sprintf("must be at most %d bytes", maxIDLen))
+	}
+}
+
+// checkReference records a violation of the given field if the entity that it
+// references does not exist, i.e. if err is [service.ErrNotFound]. Any other
+// error is returned.
+func checkReference(v *validation.Validator, field string, err error) error {
+	if errors.Is(err, service.ErrNotFound) {
+		v.Check(false, field, "references a missing entity")
+		return nil
+	}
+	return err
+}
+
+// checkDate checks that the date chosen for a booking falls within the event.
+// Events whose start time is not known accept any date, and events whose end
+// time is not known accept any date after the start.
+func checkDate(v *validation.Validator, date time.Time, event *Event) {
+	if date.IsZero() || event.Start.IsZero() {
+		return
+	}
+	inWindow := !date.Before(event.Start) && (event.End.IsZero() || !date.After(event.End))
+	v.Check(inWindow, "date", fmt.Sprintf("must be between %s and %s",
+		event.Start.Format(time.RFC3339), formatEnd(event.End)))
+}
+
+// formatEnd formats the end time of an event, which might not be known.
+func formatEnd(end time.Time) string {
+	if end.IsZero() {
+		return "the end of the event"
+	}
+	return end.Format(time.RFC3339)
+}
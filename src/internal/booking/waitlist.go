@@ -0,0 +1,195 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/auth"
+	"github.com/eventscompass/booking-service/src/internal/outbox"
+	"github.com/eventscompass/booking-service/src/internal/validation"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// JoinWaitlist adds the user to the waitlist of a sold-out event and returns
+// the position of the user in the waitlist, starting from 1. This function
+// returns a [*validation.Error] listing every violation if the entry is
+// missing required fields, sets fields managed by the service, or references a
+// user or an event that does not exist. This function returns
+// [service.ErrNotAllowed] if the event is not sold out, or if it is cancelled,
+// or if the caller waitlists another user. This function returns
+// [service.ErrAlreadyExists] if the user is already waitlisted.
+func (m *Manager) JoinWaitlist(ctx context.Context, e *WaitlistEntry) (int, error) {
+	if err := validateEntry(e); err != nil {
+		return 0, fmt.Errorf("join waitlist: %w", err)
+	}
+	if err := auth.Authorize(ctx, e.UserID); err != nil {
+		return 0, fmt.Errorf("join waitlist: %w", err)
+	}
+
+	// Make sure that the entry references existing entities.
+	var v validation.Validator
+	event, err := m.GetEvent(ctx, e.EventID)
+	if err := checkReference(&v, "event_id", err); err != nil {
+		return 0, err
+	}
+	_, err = m.getUser(ctx, e.UserID)
+	if err := checkReference(&v, "user_id", err); err != nil {
+		return 0, err
+	}
+	if err := v.Err(); err != nil {
+		return 0, err //nolint:wrapcheck // the error lists the violations
+	}
+
+	// Users should book a seat directly if there is one.
+	if event.Cancelled {
+		return 0, fmt.Errorf("%w: event %q is cancelled", service.ErrNotAllowed, e.EventID)
+	}
+	if event.Capacity == 0 || event.Booked < event.Capacity {
+		return 0, fmt.Errorf("%w: event %q is not sold out", service.ErrNotAllowed, e.EventID)
+	}
+
+	entries, err := m.bookingsDB.Waitlist(ctx, e.EventID)
+	if err != nil {
+		return 0, fmt.Errorf("get waitlist: %w", err)
+	}
+	for _, other := range entries {
+		if other.UserID == e.UserID {
+			return 0, fmt.Errorf(
+				"%w: user %q is already waitlisted", service.ErrAlreadyExists, e.UserID)
+		}
+	}
+
+	id, err := NewID()
+	if err != nil {
+		return 0, fmt.Errorf("waitlist entry id: %w", err)
+	}
+	e.ID = id
+	e.JoinedAt = time.Now().UTC()
+
+	// The scan above misses a concurrent join of the same user, which is
+	// rejected by the container instead.
+	err = m.bookingsDB.Create(ctx, WaitlistCollection, *e)
+	if errors.Is(err, service.ErrAlreadyExists) {
+		return 0, fmt.Errorf("%w: user %q is already waitlisted", service.ErrAlreadyExists, e.UserID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("create waitlist entry: %w", err)
+	}
+	return len(entries) + 1, nil
+}
+
+// WaitlistPosition retrieves the waitlist entry with the given id, together
+// with its position in the waitlist, starting from 1. This function returns
+// [service.ErrNotFound] if the entry does not exist. This function returns
+// [service.ErrNotAllowed] if the entry belongs to another user than the
+// caller.
+func (m *Manager) WaitlistPosition(ctx context.Context, id string) (*WaitlistEntry, int, error) {
+	e, err := m.getEntry(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries, err := m.bookingsDB.Waitlist(ctx, e.EventID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get waitlist: %w", err)
+	}
+	for i, other := range entries {
+		if other.ID == e.ID {
+			return e, i + 1, nil
+		}
+	}
+	// The entry was promoted or removed in the meantime.
+	return nil, 0, fmt.Errorf("%w: waitlist entry %q", service.ErrNotFound, id)
+}
+
+// LeaveWaitlist removes the waitlist entry with the given id. This function
+// returns [service.ErrNotFound] if the entry does not exist. This function
+// returns [service.ErrNotAllowed] if the entry belongs to another user than
+// the caller.
+func (m *Manager) LeaveWaitlist(ctx context.Context, id string) error {
+	if _, err := m.getEntry(ctx, id); err != nil {
+		return err
+	}
+	if err := m.bookingsDB.Delete(ctx, WaitlistCollection, id); err != nil {
+		return fmt.Errorf("delete waitlist entry: %w", err)
+	}
+	return nil
+}
+
+// getEntry retrieves the waitlist entry with the given id, if the caller may
+// access it.
+func (m *Manager) getEntry(ctx context.Context, id string) (*WaitlistEntry, error) {
+	elem, err := m.bookingsDB.GetByID(ctx, WaitlistCollection, id)
+	if err != nil {
+		return nil, fmt.Errorf("get waitlist entry: %w", err)
+	}
+	e, ok := elem.(WaitlistEntry)
+	if !ok {
+		return nil, service.Unexpected(ctx, fmt.Errorf("invalid entry type %T", elem))
+	}
+	if err := auth.Authorize(ctx, e.UserID); err != nil {
+		return nil, fmt.Errorf("get waitlist entry %q: %w", id, err)
+	}
+	return &e, nil
+}
+
+// promote gives the seat that was freed for the given event to the first user
+// in the waitlist of the event. The user gets a seat hold, which expires
+// unless it is confirmed in time, and a [WaitlistPromoted] message is stored
+// in the outbox. This function is expected to be called inside the
+// transaction which freed the seat. It returns the id of the created hold, or
+// an empty string if no user was promoted.
+func (m *Manager) promote(ctx context.Context, eventID string) (string, error) {
+	entries, err := m.bookingsDB.Waitlist(ctx, eventID)
+	if err != nil {
+		return "", fmt.Errorf("get waitlist: %w", err)
+	}
+	if len(entries) == 0 {
+		return "", nil
+	}
+	next := entries[0]
+
+	expiresAt := time.Now().UTC().Add(m.cfg.HoldTTL)
+	hold := Booking{
+		UserID:    next.UserID,
+		EventID:   next.EventID,
+		Status:    StatusPending,
+		ExpiresAt: &expiresAt,
+	}
+	if err := stamp(&hold); err != nil {
+		return "", err
+	}
+	msg, err := outbox.NewMessage(WaitlistPromotedTopic, WaitlistPromoted{
+		HoldID:    hold.ID,
+		EventID:   hold.EventID,
+		UserID:    hold.UserID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return "", fmt.Errorf("waitlist promoted message: %w", err)
+	}
+
+	// The seat might not be available if the capacity of the event was
+	// lowered in the meantime, or if the event was cancelled. The user then
+	// stays in the waitlist.
+	err = m.bookingsDB.ReserveSeats(ctx, hold.EventID, 1)
+	if errors.Is(err, service.ErrSpaceFull) || errors.Is(err, service.ErrNotAllowed) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reserve seat: %w", err)
+	}
+	if err := m.bookingsDB.Delete(ctx, WaitlistCollection, next.ID); err != nil {
+		return "", fmt.Errorf("delete waitlist entry: %w", err)
+	}
+	if err := m.bookingsDB.Create(ctx, BookingsCollection, hold); err != nil {
+		return "", fmt.Errorf("insert hold: %w", err)
+	}
+	if err := m.bookingsDB.Create(ctx, OutboxCollection, *msg); err != nil {
+		return "", fmt.Errorf("insert message: %w", err)
+	}
+	return hold.ID, nil
+}
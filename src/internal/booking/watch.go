@@ -0,0 +1,82 @@
+package booking
+
+import (
+	"context"
+	"sync"
+)
+
+// watchBuffer is the number of changes that are buffered for every watcher.
+const watchBuffer = 64
+
+// watchers broadcasts the ids of the bookings that changed to the watchers.
+// The zero value is ready to use.
+type watchers struct {
+	// mu guards chans.
+	mu sync.Mutex
+
+	// chans holds the channel of every watcher.
+	chans map[chan string]struct{}
+}
+
+// Watch streams the ids of the bookings that change from now on, i.e. of the
+// bookings that are created and of the bookings whose status changes. Only
+// the changes made through this manager are streamed, thus watchers of other
+// instances of the service do not see them. The returned channel is closed
+// once the context is done, or once the watcher falls behind by more than the
+// buffered number of changes; in the latter case the context is not done.
+func (m *Manager) Watch(ctx context.Context) <-chan string {
+	ch := make(chan string, watchBuffer)
+	w := &m.watchers
+	w.mu.Lock()
+	if w.chans == nil {
+		w.chans = make(map[chan string]struct{})
+	}
+	w.chans[ch] = struct{}{}
+	w.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		w.remove(ch)
+	}()
+	return ch
+}
+
+// notify sends the ids of the changed bookings to all watchers. Empty ids are
+// skipped. Watchers that fall behind are removed.
+func (w *watchers) notify(ids ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.chans {
+		if !send(ch, ids) {
+			delete(w.chans, ch)
+			close(ch)
+		}
+	}
+}
+
+// send sends the non-empty ids to the channel without blocking. It returns
+// false if the buffer of the channel is full.
+func send(ch chan string, ids []string) bool {
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		select {
+		case ch <- id:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// remove removes the watcher and closes its channel, unless it was already
+// removed.
+func (w *watchers) remove(ch chan string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.chans[ch]; ok {
+		delete(w.chans, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,241 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// Handler handles a received message. The returned error decides what happens
+// with the message: nil acknowledges the message, an error wrapped with
+// [Reject] sets the message aside as a dead letter right away, and any other
+// error retries the message.
+type Handler func(ctx context.Context, msg []byte) error
+
+// errRejected marks errors returned by handlers for messages which cannot be
+// handled, no matter how often they are retried.
+var errRejected = errors.New("rejected")
+
+// Reject marks the error of a message which cannot be handled, e.g. because
+// its payload is malformed. Such a message is not retried.
+func Reject(err error) error {
+	return fmt.Errorf("%w: %w", errRejected, err)
+}
+
+// Consumer runs the handlers of received messages. Failed messages are retried
+// with exponential backoff, up to a maximum number of attempts. Messages which
+// still fail, or which are rejected, are stored as dead letters in the
+// container, so that they are not lost and can be replayed later.
+type Consumer struct {
+	// bookingsDB is used to store the dead letters.
+	bookingsDB BookingsContainer
+
+	// handlers maps every topic to its handler.
+	handlers map[string]Handler
+
+	// cfg is used to configure the consumer.
+	cfg *Config
+
+	// inflight tracks the messages which are being handled.
+	inflight sync.WaitGroup
+}
+
+// Config holds configuration variables for the [Consumer].
+type Config struct {
+	// MaxAttempts is the maximum number of attempts to handle a
+	// message, before it is dead-lettered.
+	MaxAttempts int
+
+	// InitialBackoff is the time to wait before the first retry.
+	// The time doubles with every retry, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// NewConsumer creates a new [Consumer] instance.
+func NewConsumer(bookingsDB BookingsContainer, cfg *Config) *Consumer {
+	return &Consumer{
+		bookingsDB: bookingsDB,
+		handlers:   make(map[string]Handler),
+		cfg:        cfg,
+	}
+}
+
+// Handle registers the handler for the given topic and returns the
+// [service.EventHandler] to subscribe with. All handlers must be registered
+// before messages are received.
+func (c *Consumer) Handle(topic string, h Handler) service.EventHandler {
+	c.handlers[topic] = h
+	return func(ctx context.Context, msg []byte) {
+		c.inflight.Add(1)
+		defer c.inflight.Done()
+		c.consume(ctx, topic, msg)
+	}
+}
+
+// Drain blocks until all the messages which are being handled are done, or
+// until the context is done. It should be called once the subscriptions are
+// cancelled, so that no new messages are received. This function returns
+// [service.ErrUnexpected] if the context is done first.
+func (c *Consumer) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: drain handlers: %v", service.ErrUnexpected, ctx.Err())
+	}
+}
+
+// consume handles the message and dead-letters it if it cannot be handled. If
+// the dead letter cannot be stored either, then the message is requeued.
+func (c *Consumer) consume(ctx context.Context, topic string, msg []byte) {
+	attempts, err := c.handle(ctx, topic, msg)
+	if err == nil {
+		return
+	}
+
+	// The message might be dead-lettered while the service is shutting
+	// down, thus the context must not be cancelled.
+	err = c.deadLetter(context.WithoutCancel(ctx), topic, msg, attempts, err)
+	if err == nil {
+		return
+	}
+
+	// The message is neither handled nor stored, so hand it back to the
+	// message bus to be delivered again.
+	if Requeue(ctx) {
+		slog.Warn(
+			"failed to dead-letter message, requeueing",
+			slog.String("topic", topic),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	slog.Error(
+		"failed to dead-letter message",
+		slog.String("topic", topic),
+		slog.String("payload", string(msg)),
+		slog.String("error", err.Error()),
+	)
+}
+
+// handle runs the handler of the topic until it succeeds, rejects the message,
+// or the attempts are exhausted. This function returns the number of attempts
+// and the error of the last one.
+func (c *Consumer) handle(ctx context.Context, topic string, msg []byte) (int, error) {
+	h := c.handlers[topic]
+	backoff := c.cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := h(ctx, msg)
+		if err == nil || errors.Is(err, errRejected) || attempt >= c.cfg.MaxAttempts {
+			return attempt, err
+		}
+
+		slog.Warn(
+			"failed to handle message, retrying",
+			slog.String("topic", topic),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.String("error", err.Error()),
+		)
+		select {
+		case <-ctx.Done():
+			// The service is shutting down. Give up on the message, so
+			// that it is dead-lettered and can be replayed.
+			return attempt, err
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, c.cfg.MaxBackoff)
+	}
+}
+
+// deadLetter stores the message as a dead letter.
+func (c *Consumer) deadLetter(
+	ctx context.Context,
+	topic string,
+	msg []byte,
+	attempts int,
+	handleErr error,
+) error {
+	id, err := NewID()
+	if err != nil {
+		return fmt.Errorf("dead letter id: %w", err)
+	}
+	d := DeadLetter{
+		ID:        id,
+		Topic:     topic,
+		Payload:   msg,
+		Attempts:  attempts,
+		LastError: handleErr.Error(),
+		FailedAt:  time.Now().UTC(),
+	}
+	slog.Error(
+		"dead-lettering message",
+		slog.String("id", d.ID),
+		slog.String("topic", topic),
+		slog.String("error", d.LastError),
+	)
+	if err := c.bookingsDB.Create(ctx, DeadLettersCollection, d); err != nil {
+		return fmt.Errorf("insert dead letter: %w", err)
+	}
+	return nil
+}
+
+// DeadLetters retrieves up to limit dead letters, oldest first.
+func (c *Consumer) DeadLetters(ctx context.Context, limit int) ([]DeadLetter, error) {
+	msgs, err := c.bookingsDB.DeadLetters(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get dead letters: %w", err)
+	}
+	if msgs == nil {
+		msgs = []DeadLetter{}
+	}
+	return msgs, nil
+}
+
+// Replay handles the dead letter with the given id once more. The dead letter
+// is removed if the message is handled successfully, otherwise the failed
+// attempt is recorded. This function returns [service.ErrNotFound] if the dead
+// letter does not exist. This function returns [service.ErrUnexpected] if the
+// message still cannot be handled.
+func (c *Consumer) Replay(ctx context.Context, id string) error {
+	elem, err := c.bookingsDB.GetByID(ctx, DeadLettersCollection, id)
+	if err != nil {
+		return fmt.Errorf("get dead letter: %w", err)
+	}
+	d, ok := elem.(DeadLetter)
+	if !ok {
+		return service.Unexpected(ctx, fmt.Errorf("invalid dead letter type %T", elem))
+	}
+	h, ok := c.handlers[d.Topic]
+	if !ok {
+		return service.Unexpected(ctx, fmt.Errorf("no handler for topic %q", d.Topic))
+	}
+
+	if handleErr := h(ctx, d.Payload); handleErr != nil {
+		d.Attempts++
+		d.LastError = handleErr.Error()
+		d.FailedAt = time.Now().UTC()
+		if err := c.bookingsDB.Replace(ctx, DeadLettersCollection, d); err != nil {
+			return fmt.Errorf("update dead letter: %w", err)
+		}
+		return fmt.Errorf("%w: replay message: %v", service.ErrUnexpected, handleErr)
+	}
+
+	if err := c.bookingsDB.Delete(ctx, DeadLettersCollection, d.ID); err != nil {
+		return fmt.Errorf("delete dead letter: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/memory"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// failingContainer is a container which cannot store dead letters.
+type failingContainer struct {
+	*memory.MemoryContainer
+}
+
+func (c failingContainer) Create(ctx context.Context, collection string, data any) error {
+	if collection == DeadLettersCollection {
+		return fmt.Errorf("%w: database is down", service.ErrConnectionClosed)
+	}
+	return c.MemoryContainer.Create(ctx, collection, data) //nolint:wrapcheck // passed through
+}
+
+// newTestConsumer returns a consumer which makes up to three attempts to
+// handle a message.
+func newTestConsumer(t *testing.T, db BookingsContainer) *Consumer {
+	t.Helper()
+	t.Cleanup(func() { _ = db.Close() })
+	return NewConsumer(db, &Config{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	})
+}
+
+// failing returns a handler which fails the given number of times with the
+// error, and then succeeds. It counts its calls.
+func failing(times int, err error, calls *int) Handler {
+	return func(context.Context, []byte) error {
+		*calls++
+		if *calls <= times {
+			return err
+		}
+		return nil
+	}
+}
+
+func TestConsume(t *testing.T) {
+	errFailed := errors.New("database is down")
+	tests := map[string]struct {
+		failures     int
+		err          error
+		wantCalls    int
+		wantAttempts int
+	}{
+		"Handled":           {wantCalls: 1},
+		"HandledAfterRetry": {failures: 2, err: errFailed, wantCalls: 3},
+		"Exhausted":         {failures: 3, err: errFailed, wantCalls: 3, wantAttempts: 3},
+		"Rejected":          {failures: 3, err: Reject(errFailed), wantCalls: 1, wantAttempts: 1},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			db := memory.NewMemoryContainer()
+			c := newTestConsumer(t, db)
+			var calls int
+			h := c.Handle("event.created", failing(test.failures, test.err, &calls))
+
+			ctx, requeued := WithDelivery(context.Background())
+			h(ctx, []byte(`{"id":"e1"}`))
+			if calls != test.wantCalls {
+				t.Errorf("want %d calls of the handler, got %d", test.wantCalls, calls)
+			}
+			if requeued() {
+				t.Error("want the message not to be requeued")
+			}
+
+			msgs, err := c.DeadLetters(context.Background(), 10)
+			if err != nil {
+				t.Fatalf("dead letters: %v", err)
+			}
+			if test.wantAttempts == 0 {
+				if len(msgs) != 0 {
+					t.Errorf("want no dead letters, got %+v", msgs)
+				}
+				return
+			}
+			if len(msgs) != 1 {
+				t.Fatalf("want one dead letter, got %+v", msgs)
+			}
+			d := msgs[0]
+			if d.Topic != "event.created" || string(d.Payload) != `{"id":"e1"}` ||
+				d.Attempts != test.wantAttempts || d.LastError != test.err.Error() {
+				t.Errorf("want the message dead-lettered after %d attempts, got %+v",
+					test.wantAttempts, d)
+			}
+		})
+	}
+}
+
+func TestConsumeRequeues(t *testing.T) {
+	c := newTestConsumer(t, failingContainer{memory.NewMemoryContainer()})
+	var calls int
+	h := c.Handle("event.created", failing(3, Reject(errors.New("malformed")), &calls))
+
+	// The message can be neither handled nor dead-lettered, thus it is
+	// handed back to the bus, if the bus supports that.
+	ctx, requeued := WithDelivery(context.Background())
+	h(ctx, []byte(`{}`))
+	if !requeued() {
+		t.Error("want the message to be requeued")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	errFailed := errors.New("database is down")
+	tests := map[string]struct {
+		id           string
+		failures     int
+		wantErr      error
+		wantAttempts int
+	}{
+		"Handled":  {id: "d1", wantAttempts: 0},
+		"Failing":  {id: "d1", failures: 1, wantErr: service.ErrUnexpected, wantAttempts: 4},
+		"Missing":  {id: "missing", wantErr: service.ErrNotFound, wantAttempts: 3},
+		"NoHandle": {id: "d2", wantErr: service.ErrUnexpected, wantAttempts: 3},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := memory.NewMemoryContainer()
+			c := newTestConsumer(t, db)
+			var calls int
+			c.Handle("event.created", failing(test.failures, errFailed, &calls))
+
+			letters := []DeadLetter{
+				{ID: "d1", Topic: "event.created", Payload: []byte(`{}`), Attempts: 3},
+				{ID: "d2", Topic: "unknown", Payload: []byte(`{}`), Attempts: 3},
+			}
+			for _, d := range letters {
+				if err := db.Create(ctx, DeadLettersCollection, d); err != nil {
+					t.Fatalf("create dead letter: %v", err)
+				}
+			}
+
+			err := c.Replay(ctx, test.id)
+			if test.wantErr == nil && err != nil {
+				t.Fatalf("replay: %v", err)
+			}
+			if test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Fatalf("want error %v, got %v", test.wantErr, err)
+			}
+
+			// The dead letter is removed once the message is handled,
+			// otherwise the failed attempt is recorded.
+			elem, err := db.GetByID(ctx, DeadLettersCollection, "d1")
+			if test.wantAttempts == 0 {
+				if !errors.Is(err, service.ErrNotFound) {
+					t.Errorf("want the dead letter removed, got %+v and %v", elem, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("get dead letter: %v", err)
+			}
+			if d, ok := elem.(DeadLetter); !ok || d.Attempts != test.wantAttempts {
+				t.Errorf("want the dead letter after %d attempts, got %+v", test.wantAttempts, elem)
+			}
+		})
+	}
+}
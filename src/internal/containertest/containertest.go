@@ -0,0 +1,609 @@
+// Package containertest provides a conformance test suite for implementations
+// of the [BookingsContainer] interface. Every container implementation should
+// run the suite from its tests, e.g.:
+//
+//	func TestConformance(t *testing.T) {
+//		containertest.Run(t, func(t *testing.T) BookingsContainer {
+//			return NewMemoryContainer()
+//		})
+//	}
+package containertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/booking"
+	"github.com/eventscompass/booking-service/src/internal/outbox"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// NewContainer returns an empty container for a single test. The container is
+// closed once the test is finished.
+type NewContainer func(t *testing.T) BookingsContainer
+
+// Run runs the conformance test suite against the containers returned by
+// newContainer. Every test of the suite runs as a subtest with a new
+// container.
+func Run(t *testing.T, newContainer NewContainer) {
+	t.Helper()
+
+	tests := map[string]func(*testing.T, BookingsContainer){
+		"CreateAndGet":        testCreateAndGet,
+		"NotFound":            testNotFound,
+		"UnknownCollection":   testUnknownCollection,
+		"Duplicate":           testDuplicate,
+		"Replace":             testReplace,
+		"Upsert":              testUpsert,
+		"Delete":              testDelete,
+		"Capacity":            testCapacity,
+		"ConcurrentBookings":  testConcurrentBookings,
+		"CancelEvent":         testCancelEvent,
+		"UpdateStatus":        testUpdateStatus,
+		"TransactionRollback": testTransactionRollback,
+		"ListBookings":        testListBookings,
+		"ZeroLimit":           testZeroLimit,
+		"ExpiredHolds":        testExpiredHolds,
+		"Waitlist":            testWaitlist,
+		"Outbox":              testOutbox,
+		"PurgeMessages":       testPurgeMessages,
+		"DeadLetters":         testDeadLetters,
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			c := newContainer(t)
+			t.Cleanup(func() {
+				if err := c.Close(); err != nil {
+					t.Errorf("close container: %v", err)
+				}
+			})
+			test(t, c)
+		})
+	}
+}
+
+// ts returns a fixed point in time, shifted by the given number of minutes.
+// The times have no sub-millisecond part, since some databases store times with
+// millisecond precision.
+func ts(minutes int) time.Time {
+	base := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	return base.Add(time.Duration(minutes) * time.Minute)
+}
+
+func mustCreate(t *testing.T, c BookingsContainer, collection string, data any) {
+	t.Helper()
+	if err := c.Create(context.Background(), collection, data); err != nil {
+		t.Fatalf("create in %q: %v", collection, err)
+	}
+}
+
+func wantErr(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("want error %v, got %v", target, err)
+	}
+}
+
+func testCreateAndGet(t *testing.T, c BookingsContainer) {
+	want := Booking{ID: "b1", UserID: "u1", EventID: "e1", Date: ts(0), Status: StatusConfirmed}
+	mustCreate(t, c, BookingsCollection, want)
+
+	elem, err := c.GetByID(context.Background(), BookingsCollection, "b1")
+	if err != nil {
+		t.Fatalf("get booking: %v", err)
+	}
+	got, ok := elem.(Booking)
+	if !ok {
+		t.Fatalf("want type Booking, got %T", elem)
+	}
+	if got.ID != want.ID || got.UserID != want.UserID || got.EventID != want.EventID ||
+		!got.Date.Equal(want.Date) || got.Status != want.Status {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+}
+
+func testNotFound(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	for _, collection := range []string{BookingsCollection, EventsCollection, UsersCollection} {
+		_, err := c.GetByID(ctx, collection, "missing")
+		wantErr(t, err, service.ErrNotFound)
+	}
+	wantErr(t, c.Delete(ctx, BookingsCollection, "missing"), service.ErrNotFound)
+	wantErr(t, c.ReserveSeats(ctx, "missing", 1), service.ErrNotFound)
+	wantErr(t, c.UpdateStatus(ctx, "missing", StatusPending, StatusConfirmed), service.ErrNotFound)
+}
+
+func testUnknownCollection(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	_, err := c.GetByID(ctx, "unknown", "id")
+	wantErr(t, err, service.ErrNotAllowed)
+	wantErr(t, c.Create(ctx, "unknown", Booking{ID: "id"}), service.ErrNotAllowed)
+	wantErr(t, c.Delete(ctx, "unknown", "id"), service.ErrNotAllowed)
+	wantErr(t, c.SetCapacity(ctx, BookingsCollection, "id", 1), service.ErrNotAllowed)
+}
+
+func testDuplicate(t *testing.T, c BookingsContainer) {
+	mustCreate(t, c, EventsCollection, Event{ID: "e1"})
+	err := c.Create(context.Background(), EventsCollection, Event{ID: "e1", Name: "other"})
+	wantErr(t, err, service.ErrAlreadyExists)
+}
+
+func testReplace(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	mustCreate(t, c, UsersCollection, User{ID: "u1", Name: "before"})
+	if err := c.Replace(ctx, UsersCollection, User{ID: "u1", Name: "after"}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	elem, err := c.GetByID(ctx, UsersCollection, "u1")
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if u, ok := elem.(User); !ok || u.Name != "after" {
+		t.Fatalf("want replaced user, got %+v", elem)
+	}
+	wantErr(t, c.Replace(ctx, UsersCollection, User{ID: "missing"}), service.ErrNotFound)
+}
+
+func testUpsert(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	if err := c.UpsertEvent(ctx, Event{ID: "e1", Name: "v1", Version: 1}); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+	if err := c.ReserveSeats(ctx, "e1", 1); err != nil {
+		t.Fatalf("reserve seat: %v", err)
+	}
+	if err := c.UpsertEvent(ctx, Event{ID: "e1", Name: "v3", Version: 3}); err != nil {
+		t.Fatalf("update event: %v", err)
+	}
+
+	// Duplicate and stale versions are rejected.
+	wantErr(t, c.UpsertEvent(ctx, Event{ID: "e1", Name: "dup", Version: 3}), service.ErrAlreadyExists)
+	wantErr(t, c.UpsertEvent(ctx, Event{ID: "e1", Name: "v2", Version: 2}), service.ErrAlreadyExists)
+
+	elem, err := c.GetByID(ctx, EventsCollection, "e1")
+	if err != nil {
+		t.Fatalf("get event: %v", err)
+	}
+	if e, ok := elem.(Event); !ok || e.Name != "v3" || e.Booked != 1 {
+		t.Fatalf("want event v3 with 1 booked seat, got %+v", elem)
+	}
+
+	// Updates without a version are always applied, and keep the stored
+	// version.
+	if err := c.UpsertEvent(ctx, Event{ID: "e1", Name: "unversioned"}); err != nil {
+		t.Fatalf("update event without version: %v", err)
+	}
+	elem, err = c.GetByID(ctx, EventsCollection, "e1")
+	if err != nil {
+		t.Fatalf("get event: %v", err)
+	}
+	if e, ok := elem.(Event); !ok || e.Name != "unversioned" || e.Version != 3 || e.Booked != 1 {
+		t.Fatalf("want unversioned event with version 3 and 1 booked seat, got %+v", elem)
+	}
+	wantErr(t, c.UpsertEvent(ctx, Event{ID: "e1", Name: "dup", Version: 3}), service.ErrAlreadyExists)
+
+	if err := c.UpsertLocation(ctx, Location{ID: "l1", Name: "v0"}); err != nil {
+		t.Fatalf("insert location: %v", err)
+	}
+	if err := c.UpsertLocation(ctx, Location{ID: "l1", Name: "unversioned"}); err != nil {
+		t.Fatalf("update location without version: %v", err)
+	}
+	if err := c.UpsertLocation(ctx, Location{ID: "l1", Name: "v2", Version: 2}); err != nil {
+		t.Fatalf("update location: %v", err)
+	}
+	wantErr(t, c.UpsertLocation(ctx, Location{ID: "l1", Name: "v1", Version: 1}), service.ErrAlreadyExists)
+	if err := c.UpsertLocation(ctx, Location{ID: "l1", Name: "unversioned"}); err != nil {
+		t.Fatalf("update location without version: %v", err)
+	}
+	elem, err = c.GetByID(ctx, LocationsCollection, "l1")
+	if err != nil {
+		t.Fatalf("get location: %v", err)
+	}
+	if l, ok := elem.(Location); !ok || l.Name != "unversioned" || l.Version != 2 {
+		t.Fatalf("want unversioned location with version 2, got %+v", elem)
+	}
+}
+
+func testDelete(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	mustCreate(t, c, WaitlistCollection, WaitlistEntry{ID: "w1", EventID: "e1", UserID: "u1"})
+	if err := c.Delete(ctx, WaitlistCollection, "w1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	_, err := c.GetByID(ctx, WaitlistCollection, "w1")
+	wantErr(t, err, service.ErrNotFound)
+}
+
+func testCapacity(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	mustCreate(t, c, EventsCollection, Event{ID: "limited", Capacity: 2})
+	mustCreate(t, c, EventsCollection, Event{ID: "unlimited"})
+
+	for i := 0; i < 2; i++ {
+		if err := c.ReserveSeats(ctx, "limited", 1); err != nil {
+			t.Fatalf("reserve seat %d: %v", i, err)
+		}
+	}
+	wantErr(t, c.ReserveSeats(ctx, "limited", 1), service.ErrSpaceFull)
+	if err := c.ReleaseSeats(ctx, "limited", 1); err != nil {
+		t.Fatalf("release seat: %v", err)
+	}
+	if err := c.ReserveSeats(ctx, "limited", 1); err != nil {
+		t.Fatalf("reserve released seat: %v", err)
+	}
+	if err := c.ReserveSeats(ctx, "unlimited", 100); err != nil {
+		t.Fatalf("reserve unlimited seats: %v", err)
+	}
+
+	// Raising the capacity frees seats.
+	if err := c.SetCapacity(ctx, EventsCollection, "limited", 3); err != nil {
+		t.Fatalf("set capacity: %v", err)
+	}
+	if err := c.ReserveSeats(ctx, "limited", 1); err != nil {
+		t.Fatalf("reserve seat after raising capacity: %v", err)
+	}
+	wantErr(t, c.SetCapacity(ctx, LocationsCollection, "missing", 1), service.ErrNotFound)
+}
+
+// testConcurrentBookings races bookings for more seats than an event has, and
+// checks that exactly as many bookings as there are seats are made. The
+// bookings are made by a [booking.Manager], which reserves the seats in the
+// same transaction as it stores the booking and its outbox message.
+func testConcurrentBookings(t *testing.T, c BookingsContainer) {
+	const capacity, requests = 5, 20
+	ctx := context.Background()
+	start := time.Now().Add(time.Hour).UTC()
+	mustCreate(t, c, EventsCollection, Event{
+		ID: "e1", Start: start, End: start.Add(time.Hour), Capacity: capacity,
+	})
+	for i := 0; i < requests; i++ {
+		mustCreate(t, c, UsersCollection, User{ID: fmt.Sprintf("u%d", i)})
+	}
+
+	// The relay is never run, thus it needs no message bus.
+	relay := outbox.NewRelay(c, nil, &outbox.Config{})
+	m := booking.NewManager(c, relay, &booking.Config{HoldTTL: time.Minute})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			errs <- m.Create(ctx, &Booking{UserID: userID, EventID: "e1"})
+		}(fmt.Sprintf("u%d", i))
+	}
+	wg.Wait()
+	close(errs)
+
+	var booked int
+	for err := range errs {
+		switch {
+		case err == nil:
+			booked++
+		case !errors.Is(err, service.ErrSpaceFull):
+			t.Errorf("want error %v, got %v", service.ErrSpaceFull, err)
+		}
+	}
+	if booked != capacity {
+		t.Fatalf("want %d bookings, got %d", capacity, booked)
+	}
+
+	elem, err := c.GetByID(ctx, EventsCollection, "e1")
+	if err != nil {
+		t.Fatalf("get event: %v", err)
+	}
+	if e, ok := elem.(Event); !ok || e.Booked != capacity {
+		t.Errorf("want %d booked seats, got %+v", capacity, elem)
+	}
+	bookings, err := c.ListBookings(ctx, &BookingFilter{EventID: "e1", Limit: requests})
+	if err != nil || len(bookings) != capacity {
+		t.Errorf("want %d stored bookings, got %d and %v", capacity, len(bookings), err)
+	}
+	msgs, err := c.PendingMessages(ctx, requests)
+	if err != nil || len(msgs) != capacity {
+		t.Errorf("want %d outbox messages, got %d and %v", capacity, len(msgs), err)
+	}
+}
+
+func testCancelEvent(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	mustCreate(t, c, EventsCollection, Event{ID: "e1", Booked: 1})
+	if err := c.CancelEvent(ctx, "e1"); err != nil {
+		t.Fatalf("cancel event: %v", err)
+	}
+	wantErr(t, c.ReserveSeats(ctx, "e1", 1), service.ErrNotAllowed)
+	if err := c.ReleaseSeats(ctx, "e1", 1); err != nil {
+		t.Fatalf("release seat: %v", err)
+	}
+
+	// Cancelling an unknown event creates it, so that it cannot be revived
+	// by a late creation, with or without a version.
+	if err := c.CancelEvent(ctx, "e2"); err != nil {
+		t.Fatalf("cancel unknown event: %v", err)
+	}
+	if err := c.UpsertEvent(ctx, Event{ID: "e2", Name: "unversioned"}); err != nil {
+		t.Fatalf("update event without version: %v", err)
+	}
+	if err := c.UpsertEvent(ctx, Event{ID: "e2", Name: "late", Version: 1}); err != nil {
+		t.Fatalf("update event: %v", err)
+	}
+	elem, err := c.GetByID(ctx, EventsCollection, "e2")
+	if err != nil {
+		t.Fatalf("get event: %v", err)
+	}
+	if e, ok := elem.(Event); !ok || !e.Cancelled || e.Name != "late" {
+		t.Fatalf("want cancelled event, got %+v", elem)
+	}
+}
+
+func testUpdateStatus(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	mustCreate(t, c, BookingsCollection, Booking{ID: "b1", Date: ts(0), Status: StatusPending})
+
+	if err := c.UpdateStatus(ctx, "b1", StatusPending, StatusConfirmed); err != nil {
+		t.Fatalf("update status: %v", err)
+	}
+	err := c.UpdateStatus(ctx, "b1", StatusPending, StatusExpired)
+	wantErr(t, err, service.ErrNotAllowed)
+
+	elem, err := c.GetByID(ctx, BookingsCollection, "b1")
+	if err != nil {
+		t.Fatalf("get booking: %v", err)
+	}
+	b, ok := elem.(Booking)
+	if !ok || b.Status != StatusConfirmed {
+		t.Fatalf("want %q booking, got %+v", StatusConfirmed, elem)
+	}
+	if b.UpdatedAt.IsZero() {
+		t.Fatalf("want update time to be set, got %+v", b)
+	}
+}
+
+//nolint:goerr113 // the error is only used to abort the transaction
+func testTransactionRollback(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	mustCreate(t, c, EventsCollection, Event{ID: "e1", Capacity: 1})
+
+	errAbort := errors.New("abort")
+	err := c.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := c.ReserveSeats(ctx, "e1", 1); err != nil {
+			return fmt.Errorf("reserve seat: %w", err)
+		}
+		if err := c.Create(ctx, BookingsCollection, Booking{ID: "b1", Date: ts(0)}); err != nil {
+			return fmt.Errorf("create booking: %w", err)
+		}
+		return errAbort
+	})
+	wantErr(t, err, errAbort)
+
+	// Neither the booking nor the reserved seat should be committed.
+	_, err = c.GetByID(ctx, BookingsCollection, "b1")
+	wantErr(t, err, service.ErrNotFound)
+	if err := c.ReserveSeats(ctx, "e1", 1); err != nil {
+		t.Fatalf("reserve seat after rollback: %v", err)
+	}
+}
+
+func testListBookings(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	for i, userID := range []string{"u1", "u2", "u1", "u1", "u2"} {
+		mustCreate(t, c, BookingsCollection, Booking{
+			ID:     fmt.Sprintf("b%d", i),
+			UserID: userID,
+			Date:   ts(i),
+			Status: StatusConfirmed,
+		})
+	}
+
+	// List the bookings of u1 in pages of two.
+	var ids []string
+	f := &BookingFilter{UserID: "u1", Limit: 2}
+	for {
+		bookings, err := c.ListBookings(ctx, f)
+		if err != nil {
+			t.Fatalf("list bookings: %v", err)
+		}
+		for _, b := range bookings {
+			ids = append(ids, b.ID)
+		}
+		if len(bookings) < f.Limit {
+			break
+		}
+		last := bookings[len(bookings)-1]
+		f.AfterDate, f.AfterID = last.Date, last.ID
+	}
+	if fmt.Sprint(ids) != "[b0 b2 b3]" {
+		t.Fatalf("want bookings [b0 b2 b3], got %v", ids)
+	}
+
+	// The interval is closed on the left and open on the right.
+	bookings, err := c.ListBookings(ctx, &BookingFilter{From: ts(1), To: ts(3), Limit: 10})
+	if err != nil {
+		t.Fatalf("list bookings: %v", err)
+	}
+	if len(bookings) != 2 || bookings[0].ID != "b1" || bookings[1].ID != "b2" {
+		t.Fatalf("want bookings [b1 b2], got %+v", bookings)
+	}
+}
+
+// testZeroLimit checks that a zero limit means no limit for all the listings.
+func testZeroLimit(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	expired := ts(0)
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("%d", i)
+		mustCreate(t, c, BookingsCollection,
+			Booking{ID: "b" + id, Date: ts(i), Status: StatusPending, ExpiresAt: &expired})
+		mustCreate(t, c, OutboxCollection,
+			OutboxMessage{ID: "m" + id, Topic: "t", CreatedAt: ts(i)})
+		mustCreate(t, c, DeadLettersCollection,
+			DeadLetter{ID: "d" + id, Topic: "t", FailedAt: ts(i)})
+	}
+
+	listings := map[string]func() (int, error){
+		"ListBookings": func() (int, error) {
+			bookings, err := c.ListBookings(ctx, &BookingFilter{Limit: 0})
+			return len(bookings), err
+		},
+		"ExpiredHolds": func() (int, error) {
+			holds, err := c.ExpiredHolds(ctx, ts(5), 0)
+			return len(holds), err
+		},
+		"PendingMessages": func() (int, error) {
+			msgs, err := c.PendingMessages(ctx, 0)
+			return len(msgs), err
+		},
+		"DeadLetters": func() (int, error) {
+			msgs, err := c.DeadLetters(ctx, 0)
+			return len(msgs), err
+		},
+	}
+	for name, list := range listings {
+		if n, err := list(); err != nil || n != 3 {
+			t.Errorf("%s: want all 3 entries, got %d and %v", name, n, err)
+		}
+	}
+}
+
+func testExpiredHolds(t *testing.T, c BookingsContainer) {
+	expired, valid := ts(0), ts(10)
+	mustCreate(t, c, BookingsCollection,
+		Booking{ID: "expired", Date: ts(0), Status: StatusPending, ExpiresAt: &expired})
+	mustCreate(t, c, BookingsCollection,
+		Booking{ID: "valid", Date: ts(0), Status: StatusPending, ExpiresAt: &valid})
+	mustCreate(t, c, BookingsCollection,
+		Booking{ID: "confirmed", Date: ts(0), Status: StatusConfirmed, ExpiresAt: &expired})
+
+	holds, err := c.ExpiredHolds(context.Background(), ts(5), 10)
+	if err != nil {
+		t.Fatalf("expired holds: %v", err)
+	}
+	if len(holds) != 1 || holds[0].ID != "expired" {
+		t.Fatalf("want hold [expired], got %+v", holds)
+	}
+}
+
+func testWaitlist(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	mustCreate(t, c, WaitlistCollection,
+		WaitlistEntry{ID: "w2", EventID: "e1", UserID: "u2", JoinedAt: ts(2)})
+	mustCreate(t, c, WaitlistCollection,
+		WaitlistEntry{ID: "w1", EventID: "e1", UserID: "u1", JoinedAt: ts(1)})
+	mustCreate(t, c, WaitlistCollection,
+		WaitlistEntry{ID: "w3", EventID: "e2", UserID: "u1", JoinedAt: ts(0)})
+
+	entries, err := c.Waitlist(ctx, "e1")
+	if err != nil {
+		t.Fatalf("waitlist: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != "w1" || entries[1].ID != "w2" {
+		t.Fatalf("want entries [w1 w2], got %+v", entries)
+	}
+
+	// A user can be waitlisted only once for the same event.
+	err = c.Create(ctx, WaitlistCollection,
+		WaitlistEntry{ID: "w4", EventID: "e1", UserID: "u1", JoinedAt: ts(3)})
+	wantErr(t, err, service.ErrAlreadyExists)
+}
+
+//nolint:goerr113 // the error is only recorded
+func testOutbox(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	mustCreate(t, c, OutboxCollection, OutboxMessage{ID: "m2", Topic: "t", CreatedAt: ts(2)})
+	mustCreate(t, c, OutboxCollection, OutboxMessage{ID: "m1", Topic: "t", CreatedAt: ts(1)})
+
+	if err := c.RecordAttempt(ctx, "m1", errors.New("broker down")); err != nil {
+		t.Fatalf("record failed attempt: %v", err)
+	}
+	msgs, err := c.PendingMessages(ctx, 10)
+	if err != nil {
+		t.Fatalf("pending messages: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].ID != "m1" || msgs[1].ID != "m2" {
+		t.Fatalf("want messages [m1 m2], got %+v", msgs)
+	}
+	if msgs[0].Attempts != 1 || msgs[0].LastError != "broker down" {
+		t.Fatalf("want one failed attempt, got %+v", msgs[0])
+	}
+
+	if err := c.RecordAttempt(ctx, "m1", nil); err != nil {
+		t.Fatalf("record successful attempt: %v", err)
+	}
+	msgs, err = c.PendingMessages(ctx, 10)
+	if err != nil {
+		t.Fatalf("pending messages: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != "m2" {
+		t.Fatalf("want messages [m2], got %+v", msgs)
+	}
+	wantErr(t, c.RecordAttempt(ctx, "missing", nil), service.ErrNotFound)
+
+	if err := c.ParkMessage(ctx, "m2"); err != nil {
+		t.Fatalf("park message: %v", err)
+	}
+	msgs, err = c.PendingMessages(ctx, 10)
+	if err != nil {
+		t.Fatalf("pending messages: %v", err)
+	}
+	if len(msgs) != 0 {
+		t.Fatalf("want no messages, got %+v", msgs)
+	}
+	wantErr(t, c.ParkMessage(ctx, "missing"), service.ErrNotFound)
+}
+
+func testPurgeMessages(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	mustCreate(t, c, OutboxCollection, OutboxMessage{ID: "m1", Topic: "t", CreatedAt: ts(1)})
+	mustCreate(t, c, OutboxCollection, OutboxMessage{ID: "m2", Topic: "t", CreatedAt: ts(2)})
+	mustCreate(t, c, OutboxCollection, OutboxMessage{ID: "m3", Topic: "t", CreatedAt: ts(3)})
+	if err := c.RecordAttempt(ctx, "m1", nil); err != nil {
+		t.Fatalf("record successful attempt: %v", err)
+	}
+	if err := c.ParkMessage(ctx, "m3"); err != nil {
+		t.Fatalf("park message: %v", err)
+	}
+
+	// Only the sent and the parked messages are purged, and only once
+	// they were settled before the given time.
+	n, err := c.PurgeMessages(ctx, time.Now().Add(-time.Hour))
+	if err != nil || n != 0 {
+		t.Fatalf("want no purged messages, got %d and %v", n, err)
+	}
+	n, err = c.PurgeMessages(ctx, time.Now().Add(time.Hour))
+	if err != nil || n != 2 {
+		t.Fatalf("want 2 purged messages, got %d and %v", n, err)
+	}
+	for _, id := range []string{"m1", "m3"} {
+		_, err = c.GetByID(ctx, OutboxCollection, id)
+		wantErr(t, err, service.ErrNotFound)
+	}
+	msgs, err := c.PendingMessages(ctx, 10)
+	if err != nil {
+		t.Fatalf("pending messages: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != "m2" {
+		t.Fatalf("want messages [m2], got %+v", msgs)
+	}
+}
+
+func testDeadLetters(t *testing.T, c BookingsContainer) {
+	ctx := context.Background()
+	mustCreate(t, c, DeadLettersCollection, DeadLetter{ID: "d2", Topic: "t", FailedAt: ts(2)})
+	mustCreate(t, c, DeadLettersCollection, DeadLetter{ID: "d1", Topic: "t", FailedAt: ts(1)})
+	mustCreate(t, c, DeadLettersCollection, DeadLetter{ID: "d3", Topic: "t", FailedAt: ts(3)})
+
+	msgs, err := c.DeadLetters(ctx, 2)
+	if err != nil {
+		t.Fatalf("dead letters: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].ID != "d1" || msgs[1].ID != "d2" {
+		t.Fatalf("want messages [d1 d2], got %+v", msgs)
+	}
+}
@@ -10,8 +10,15 @@ import (
 type BookingsContainer interface {
 	io.Closer
 
+	// Ping checks that the container is reachable. This function
+	// returns [service.ErrConnectionClosed] if it is not.
+	Ping(context.Context) error
+
 	// Create creates a new entry in the given collection in the
-	// container.
+	// container. This function returns [service.ErrAlreadyExists]
+	// if an entry with the same id is already in the collection.
+	// This function returns [service.ErrNotAllowed] if the requested
+	// collection is not in the container.
 	Create(_ context.Context, collection string, data any) error
 
 	// GetByID retrieves the entry with the given id from the
@@ -20,14 +27,184 @@ type BookingsContainer interface {
 	// container. This function returns [service.ErrNotAllowed]
 	// if the requested collection is not in the container.
 	GetByID(_ context.Context, collection string, id string) (any, error)
+
+	// Replace replaces the entry with the same id as data in the
+	// given collection in the container. This function returns
+	// [service.ErrNotFound] if the requested item is not in the
+	// container. This function returns [service.ErrNotAllowed] if
+	// the requested collection is not in the container.
+	Replace(_ context.Context, collection string, data any) error
+
+	// UpsertEvent creates the given event, or updates the event with
+	// the same id, provided that the stored event has a lower
+	// version. The number of booked seats of a stored event is kept,
+	// and so is its cancellation. An event without a version always
+	// updates the stored event, and keeps its version.
+	// This function returns [service.ErrAlreadyExists] if the stored
+	// event has the same or a newer version.
+	UpsertEvent(_ context.Context, e Event) error
+
+	// UpsertLocation creates the given location, or updates the
+	// location with the same id, provided that the stored location
+	// has a lower version. A location without a version always
+	// updates the stored location, and keeps its version. This
+	// function returns [service.ErrAlreadyExists] if the stored
+	// location has the same or a newer version.
+	UpsertLocation(_ context.Context, l Location) error
+
+	// Delete deletes the entry with the given id from the given
+	// collection in the container. This function returns
+	// [service.ErrNotFound] if the requested item is not in the
+	// container. This function returns [service.ErrNotAllowed] if
+	// the requested collection is not in the container.
+	Delete(_ context.Context, collection string, id string) error
+
+	// SetCapacity sets the capacity of the entry with the given id
+	// from the given collection. Only events and locations have a
+	// capacity. This function returns [service.ErrNotFound] if the
+	// requested item is not in the container. This function returns
+	// [service.ErrNotAllowed] if the entries from the requested
+	// collection do not have a capacity.
+	SetCapacity(_ context.Context, collection string, id string, capacity int) error
+
+	// CancelEvent marks the event with the given id as cancelled. If
+	// the event is not in the container, then a cancelled event is
+	// created, so that a late message about its creation does not
+	// revive it.
+	CancelEvent(_ context.Context, id string) error
+
+	// ReserveSeats atomically reserves the given number of seats for
+	// the event with the given id. This function returns
+	// [service.ErrNotFound] if the event is not in the container.
+	// This function returns [service.ErrSpaceFull] if the event
+	// does not have enough free seats. This function returns
+	// [service.ErrNotAllowed] if the event is cancelled.
+	ReserveSeats(_ context.Context, eventID string, seats int) error
+
+	// ReleaseSeats atomically releases the given number of booked
+	// seats for the event with the given id. This function returns
+	// [service.ErrNotFound] if the event is not in the container.
+	ReleaseSeats(_ context.Context, eventID string, seats int) error
+
+	// UpdateStatus atomically changes the status of the booking with
+	// the given id, provided that the booking currently has the
+	// status from. This function returns [service.ErrNotFound] if
+	// the booking is not in the container. This function returns
+	// [service.ErrNotAllowed] if the booking does not have the
+	// status from. The update time of the booking is set to the
+	// current time.
+	UpdateStatus(_ context.Context, id string, from, to BookingStatus) error
+
+	// ListBookings retrieves the bookings that match the filter,
+	// ordered by their date and id.
+	ListBookings(_ context.Context, f *BookingFilter) ([]Booking, error)
+
+	// ExpiredHolds retrieves up to limit pending bookings that
+	// expired before the given time. A zero limit means no limit.
+	ExpiredHolds(_ context.Context, before time.Time, limit int) ([]Booking, error)
+
+	// Waitlist retrieves the waitlist entries for the event with the
+	// given id, in the order in which the users joined the waitlist.
+	Waitlist(_ context.Context, eventID string) ([]WaitlistEntry, error)
+
+	// WithTransaction executes fn inside a transaction. All the
+	// operations that fn performs on the container using the
+	// provided context are either committed together, or none of
+	// them is committed. The error returned by fn is returned by
+	// this function.
+	WithTransaction(_ context.Context, fn func(context.Context) error) error
+
+	// PendingMessages retrieves up to limit messages from the
+	// [OutboxCollection] that have neither been sent nor parked yet,
+	// ordered by their creation time. A zero limit means no limit.
+	PendingMessages(_ context.Context, limit int) ([]OutboxMessage, error)
+
+	// RecordAttempt records an attempt to send the outbox message
+	// with the given id. If sendErr is nil, then the message is
+	// marked as sent. This function returns [service.ErrNotFound]
+	// if the message is not in the container.
+	RecordAttempt(_ context.Context, id string, sendErr error) error
+
+	// ParkMessage sets the outbox message with the given id aside,
+	// so that it is no longer pending. This function returns
+	// [service.ErrNotFound] if the message is not in the container.
+	ParkMessage(_ context.Context, id string) error
+
+	// PurgeMessages removes the messages from the [OutboxCollection]
+	// that were sent or parked before the given time. It returns the
+	// number of removed messages.
+	PurgeMessages(_ context.Context, before time.Time) (int, error)
+
+	// DeadLetters retrieves up to limit messages from the
+	// [DeadLettersCollection], ordered by the time at which they
+	// were dead-lettered. A zero limit means no limit.
+	DeadLetters(_ context.Context, limit int) ([]DeadLetter, error)
 }
 
 // Booking represents a booking entry in the container.
 type Booking struct {
-	ID      string    `json:"id"`
-	UserID  string    `json:"user_id"`
-	EventID string    `json:"event_id"`
-	Date    time.Time `json:"date"`
+	ID      string        `json:"id"`
+	UserID  string        `json:"user_id"`
+	EventID string        `json:"event_id"`
+	Date    time.Time     `json:"date"`
+	Status  BookingStatus `json:"status"`
+
+	// ExpiresAt is the time at which a pending booking, i.e. a
+	// seat hold, expires, unless it is confirmed.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// CreatedAt and UpdatedAt are the times at which the booking
+	// was created and last changed.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BookingStatus is the status of a booking in its lifecycle.
+type BookingStatus string
+
+const (
+	// StatusPending is the status of a booking that is not yet
+	// confirmed.
+	StatusPending BookingStatus = "pending"
+
+	// StatusConfirmed is the status of a booking for which a seat
+	// was reserved.
+	StatusConfirmed BookingStatus = "confirmed"
+
+	// StatusCancelled is the status of a booking that was cancelled
+	// and whose seat was given back to the event.
+	StatusCancelled BookingStatus = "cancelled"
+
+	// StatusCheckedIn is the status of a booking whose user checked
+	// in at the event.
+	StatusCheckedIn BookingStatus = "checked-in"
+
+	// StatusExpired is the status of a pending booking that was not
+	// confirmed in time.
+	StatusExpired BookingStatus = "expired"
+)
+
+// BookingFilter is used to select the bookings that are listed from the
+// container. Empty fields are not used for filtering.
+type BookingFilter struct {
+	UserID  string
+	EventID string
+	Status  BookingStatus
+
+	// From and To select the bookings whose date falls in the
+	// interval [From, To).
+	From time.Time
+	To   time.Time
+
+	// AfterDate and AfterID select the bookings that come after the
+	// booking with the given date and id in the listing order. They
+	// are used for pagination.
+	AfterDate time.Time
+	AfterID   string
+
+	// Limit is the maximum number of bookings to list. Zero means
+	// no limit.
+	Limit int
 }
 
 // User represents a user entry in the container.
@@ -38,15 +215,111 @@ type User struct {
 
 // Event represents an event entry in the container.
 type Event struct {
-	ID         string
-	Name       string
-	LocationID string
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	LocationID string    `json:"location_id"`
+	Start      time.Time `json:"start_time"`
+	End        time.Time `json:"end_time"`
+
+	// Capacity is the maximum number of seats that can be booked
+	// for the event. Zero means that the number is not limited.
+	Capacity int `json:"capacity"`
+
+	// Booked is the number of seats that are already booked.
+	Booked int `json:"booked"`
+
+	// Version is the version of the event as announced by the
+	// service owning the event. It is used to ignore stale updates.
+	// Zero means that the event is not versioned.
+	Version int64 `json:"version"`
+
+	// Cancelled is set once the event is cancelled. No seats can be
+	// reserved for a cancelled event.
+	Cancelled bool `json:"cancelled"`
 }
 
 // Location represents a location entry in the container.
 type Location struct {
-	ID   string
-	Name string
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// Capacity is the default capacity of the events hosted at the
+	// location. Zero means that the capacity is not limited.
+	Capacity int `json:"capacity"`
+
+	// Version is the version of the location as announced by the
+	// service owning the location. It is used to ignore stale
+	// updates. Zero means that the location is not versioned.
+	Version int64 `json:"version"`
+}
+
+// WaitlistEntry represents a waitlist entry in the container. A user joins the
+// waitlist of an event when the event is sold out.
+type WaitlistEntry struct {
+	ID       string    `json:"id"`
+	EventID  string    `json:"event_id"`
+	UserID   string    `json:"user_id"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// IdempotencyRecord represents a record of a request, which was made with an
+// idempotency key, and of the response to that request. The id of the record
+// is the idempotency key, scoped to the caller that made the request.
+type IdempotencyRecord struct {
+	ID string
+
+	// Fingerprint identifies the request, so that the key cannot be
+	// reused for a different request.
+	Fingerprint string
+
+	// Completed is set once the response is recorded. Until then
+	// the request is still in progress.
+	Completed  bool
+	StatusCode int
+	Header     map[string][]string
+	Body       []byte
+
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// OutboxMessage represents a message entry in the container, which is waiting
+// to be sent to the message bus.
+type OutboxMessage struct {
+	ID        string
+	Topic     string
+	Payload   []byte
+	CreatedAt time.Time
+
+	// Sent is set once the message is successfully published.
+	Sent   bool
+	SentAt time.Time
+
+	// Attempts is the number of attempts to publish the message,
+	// and LastError is the error from the last failed attempt.
+	Attempts  int
+	LastError string
+
+	// Parked is set once the relay gives up on publishing the
+	// message. The message is kept for inspection until purged.
+	Parked   bool
+	ParkedAt time.Time
+}
+
+// DeadLetter represents a received message entry in the container, which could
+// not be handled and was set aside for inspection and replay.
+type DeadLetter struct {
+	ID      string `json:"id"`
+	Topic   string `json:"topic"`
+	Payload []byte `json:"payload"`
+
+	// Attempts is the number of attempts to handle the message,
+	// and LastError is the error from the last failed attempt.
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error"`
+
+	// FailedAt is the time of the last failed attempt.
+	FailedAt time.Time `json:"failed_at"`
 }
 
 var (
@@ -61,4 +334,32 @@ var (
 
 	// UsersCollection is the name of the collection where users will be stored.
 	UsersCollection = "users"
+
+	// WaitlistCollection is the name of the collection where waitlist entries
+	// will be stored.
+	WaitlistCollection = "waitlist"
+
+	// OutboxCollection is the name of the collection where messages will be
+	// stored until they are sent to the message bus.
+	OutboxCollection = "outbox"
+
+	// IdempotencyCollection is the name of the collection where the records
+	// of requests made with idempotency keys will be stored.
+	IdempotencyCollection = "idempotency"
+
+	// DeadLettersCollection is the name of the collection where received
+	// messages that could not be handled will be stored.
+	DeadLettersCollection = "deadletters"
 )
+
+// KnownCollection returns true if the collection with the given name is one of
+// the collections in the container.
+func KnownCollection(name string) bool {
+	switch name {
+	case BookingsCollection, EventsCollection, LocationsCollection, UsersCollection,
+		WaitlistCollection, OutboxCollection, IdempotencyCollection, DeadLettersCollection:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUndeliverable classifies the errors of published messages which the
+// message bus cannot deliver, no matter how often they are published again,
+// e.g. because no one is subscribed to their topic.
+var ErrUndeliverable = errors.New("undeliverable")
+
+// The [service.EventHandler] of the framework does not return a result, thus a
+// message bus cannot tell whether a received message was handled. Instead, the
+// bus attaches a delivery to the context of the handler, through which the
+// handler asks for the message to be delivered again.
+
+// delivery records the outcome of handling a received message.
+type delivery struct {
+	requeue bool
+}
+
+// deliveryKey is the context key under which the delivery is stored.
+type deliveryKey struct{}
+
+// WithDelivery returns a copy of the context, through which the handler of a
+// received message can call [Requeue]. The returned function reports whether
+// the handler did so, and should be called once the handler returns.
+func WithDelivery(ctx context.Context) (context.Context, func() bool) {
+	d := new(delivery)
+	return context.WithValue(ctx, deliveryKey{}, d), func() bool { return d.requeue }
+}
+
+// Requeue asks the message bus to deliver the message, whose handler received
+// the context, again instead of acknowledging it. It is meant for messages that
+// could not be handled nor stored elsewhere, so that they are not lost. This
+// function returns false if the bus does not support redelivery.
+func Requeue(ctx context.Context) bool {
+	d, ok := ctx.Value(deliveryKey{}).(*delivery)
+	if ok {
+		d.requeue = true
+	}
+	return ok
+}
@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+// errNotStarted is the status of a subscription that has not started yet.
+var errNotStarted = errors.New("not started")
+
+// Bus wraps a [service.MessageBus] and tracks the status of its subscriptions,
+// so that a stopped subscription makes the service unhealthy.
+type Bus struct {
+	service.MessageBus
+
+	// mu guards subs.
+	mu sync.Mutex
+
+	// subs maps the topic of every subscription to its status: nil
+	// while the subscription is running, otherwise the reason why
+	// it is not running.
+	subs map[string]error
+}
+
+// TrackSubscriptions wraps the given bus in order to track its subscriptions.
+func TrackSubscriptions(bus service.MessageBus) *Bus {
+	return &Bus{
+		MessageBus: bus,
+		subs:       make(map[string]error),
+	}
+}
+
+// Expect registers the topics to which the service is expected to subscribe.
+// The subscriptions are unhealthy until they are started.
+func (b *Bus) Expect(topics ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, topic := range topics {
+		if _, ok := b.subs[topic]; !ok {
+			b.subs[topic] = errNotStarted
+		}
+	}
+}
+
+// Subscribe implements the [service.MessageBus] interface. The subscription
+// is marked as running until the underlying subscription returns.
+func (b *Bus) Subscribe(ctx context.Context, topic string, h service.EventHandler) error {
+	b.set(topic, nil)
+	err := b.MessageBus.Subscribe(ctx, topic, h)
+
+	status := err
+	if status == nil {
+		status = errors.New("stopped") //nolint:goerr113 // the error is only reported
+	}
+	b.set(topic, status)
+	return err //nolint:wrapcheck // the error of the underlying bus is passed through
+}
+
+func (b *Bus) set(topic string, status error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[topic] = status
+}
+
+// Connected returns true if the underlying bus is connected. Buses which do not
+// report their connection are assumed to be connected.
+func (b *Bus) Connected() bool {
+	if c, ok := b.MessageBus.(interface{ Connected() bool }); ok {
+		return c.Connected()
+	}
+	return true
+}
+
+// CheckConnection is a [Check] which fails if the bus is not connected.
+func (b *Bus) CheckConnection(context.Context) error {
+	if !b.Connected() {
+		return fmt.Errorf("%w: message bus is disconnected", service.ErrConnectionClosed)
+	}
+	return nil
+}
+
+// CheckSubscriptions is a [Check] which fails if any subscription is not
+// running.
+func (b *Bus) CheckSubscriptions(context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var failed []string
+	for topic, status := range b.subs {
+		if status != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", topic, status))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	slices.Sort(failed)
+	return fmt.Errorf("%w: subscriptions not running: %s",
+		service.ErrUnexpected, strings.Join(failed, "; "))
+}
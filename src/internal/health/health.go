@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// StatusUp is the status of a healthy dependency.
+	StatusUp = "up"
+
+	// StatusDown is the status of an unhealthy dependency.
+	StatusDown = "down"
+)
+
+// Check checks the status of a dependency of the service. It returns an error
+// if the dependency is unhealthy.
+type Check func(context.Context) error
+
+// Checker runs the checks of the dependencies of the service, in order to tell
+// whether the service is ready to serve requests. It serves the results over
+// http and is safe for concurrent use once all checks are added.
+type Checker struct {
+	// checks maps the name of every dependency to its check.
+	checks map[string]Check
+
+	// timeout is the maximum time that a check is allowed to take.
+	timeout time.Duration
+}
+
+// NewChecker creates a new [Checker] instance, whose checks time out after the
+// given timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		checks:  make(map[string]Check),
+		timeout: timeout,
+	}
+}
+
+// Add adds the check of the dependency with the given name.
+func (c *Checker) Add(name string, check Check) {
+	c.checks[name] = check
+}
+
+// Report is the outcome of running all the checks.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Result is the outcome of checking a dependency. The error of a failed check
+// is not served, because it may reveal the internals of the service.
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Err       error   `json:"-"`
+}
+
+// Run runs all the checks concurrently and reports their results. The service
+// is up only if all its dependencies are up.
+func (c *Checker) Run(ctx context.Context) *Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	report := &Report{Status: StatusUp, Checks: make(map[string]Result, len(c.checks))}
+	for name, check := range c.checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check(ctx)
+			res := Result{
+				Status:    StatusUp,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+				Err:       err,
+			}
+			if err != nil {
+				res.Status = StatusDown
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = res
+			if err != nil {
+				report.Status = StatusDown
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return report
+}
+
+// ServeHTTP implements the [http.Handler] interface. It responds with the
+// status and the latency of every dependency, and with 503 Service Unavailable
+// if any of them is down. The errors of the failed checks are only logged.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+	for name, res := range report.Checks {
+		if res.Err != nil {
+			slog.Warn("dependency is down",
+				slog.String("dependency", name),
+				slog.Float64("latency_ms", res.LatencyMS),
+				slog.String("error", res.Err.Error()),
+			)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf8")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != StatusUp {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
+}
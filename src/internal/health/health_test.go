@@ -0,0 +1,130 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timeout is the timeout of the checks in the tests.
+const timeout = 20 * time.Millisecond
+
+func up(context.Context) error { return nil }
+
+func down(context.Context) error { return errors.New("mongodb://user:secret@db: refused") }
+
+// hang blocks until the context is done.
+func hang(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRun(t *testing.T) {
+	tests := map[string]struct {
+		checks     map[string]Check
+		wantStatus string
+		wantChecks map[string]string
+	}{
+		"NoChecks": {
+			wantStatus: StatusUp,
+			wantChecks: map[string]string{},
+		},
+		"Up": {
+			checks:     map[string]Check{"database": up, "bus": up},
+			wantStatus: StatusUp,
+			wantChecks: map[string]string{"database": StatusUp, "bus": StatusUp},
+		},
+		"OneDown": {
+			checks:     map[string]Check{"database": down, "bus": up},
+			wantStatus: StatusDown,
+			wantChecks: map[string]string{"database": StatusDown, "bus": StatusUp},
+		},
+		"TimedOut": {
+			checks:     map[string]Check{"database": hang, "bus": up},
+			wantStatus: StatusDown,
+			wantChecks: map[string]string{"database": StatusDown, "bus": StatusUp},
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			c := NewChecker(timeout)
+			for name, check := range test.checks {
+				c.Add(name, check)
+			}
+
+			report := c.Run(context.Background())
+			if report.Status != test.wantStatus {
+				t.Errorf("want status %q, got %q", test.wantStatus, report.Status)
+			}
+			if len(report.Checks) != len(test.wantChecks) {
+				t.Fatalf("want checks %v, got %+v", test.wantChecks, report.Checks)
+			}
+			for name, status := range test.wantChecks {
+				res := report.Checks[name]
+				if res.Status != status {
+					t.Errorf("%s: want status %q, got %q", name, status, res.Status)
+				}
+				if (status == StatusDown) != (res.Err != nil) {
+					t.Errorf("%s: want an error only if down, got %v", name, res.Err)
+				}
+			}
+		})
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	c := NewChecker(timeout)
+	c.Add("database", hang)
+	c.Add("bus", hang)
+
+	start := time.Now()
+	report := c.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > 10*timeout {
+		t.Errorf("want the checks to time out after %v, took %v", timeout, elapsed)
+	}
+	for name, res := range report.Checks {
+		if !errors.Is(res.Err, context.DeadlineExceeded) {
+			t.Errorf("%s: want error %v, got %v", name, context.DeadlineExceeded, res.Err)
+		}
+		if res.LatencyMS < float64(timeout.Milliseconds()) {
+			t.Errorf("%s: want a latency of at least %v, got %vms", name, timeout, res.LatencyMS)
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	c := NewChecker(timeout)
+	c.Add("database", down)
+	c.Add("bus", up)
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("want status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("want the error of the check to be hidden, got %s", w.Body)
+	}
+
+	var body struct {
+		Status string                    `json:"status"`
+		Checks map[string]map[string]any `json:"checks"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if body.Status != StatusDown || len(body.Checks) != 2 {
+		t.Fatalf("want both checks in a down report, got %+v", body)
+	}
+	for name, res := range body.Checks {
+		if _, ok := res["latency_ms"].(float64); !ok || len(res) != 2 {
+			t.Errorf("%s: want only the status and the latency, got %v", name, res)
+		}
+	}
+}
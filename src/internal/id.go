@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+// NewID generates a new id for an entry in the container. The id is a version
+// 7 UUID as defined in RFC 9562, i.e. it starts with the current unix time in
+// milliseconds followed by random bits. Thus ids sort by their creation time.
+func NewID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[6:]); err != nil {
+		return "", fmt.Errorf("%w: generate id: %v", service.ErrUnexpected, err)
+	}
+
+	// The first 48 bits hold the timestamp.
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixMilli()))
+	copy(id[:6], ms[2:])
+
+	id[6] = id[6]&0x0f | 0x70 // version 7
+	id[8] = id[8]&0x3f | 0x80 // variant 10
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+	return string(buf[:]), nil
+}
@@ -0,0 +1,284 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/auth"
+	"github.com/eventscompass/booking-service/src/internal/problem"
+	"github.com/eventscompass/booking-service/src/internal/validation"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// Header is the http header with which clients provide the idempotency key.
+const Header = "Idempotency-Key"
+
+// Keys makes requests idempotent with respect to the idempotency key provided
+// by the client. The first request made with a given key is executed and its
+// response is recorded. Retries with the same key get the recorded response,
+// without executing the request again. Keys are scoped to the caller, so that
+// different callers may use the same key.
+type Keys struct {
+	// bookingsDB is the container storing the records of the requests.
+	bookingsDB BookingsContainer
+
+	// ttl is the time for which a key is stored.
+	ttl time.Duration
+}
+
+// NewKeys creates a new [Keys] instance. Keys expire after the given ttl.
+func NewKeys(bookingsDB BookingsContainer, ttl time.Duration) *Keys {
+	return &Keys{
+		bookingsDB: bookingsDB,
+		ttl:        ttl,
+	}
+}
+
+// Middleware wraps the given handler, so that requests with an idempotency
+// key are executed at most once. Requests without a key are passed through.
+// A request that reuses a key with a different method, path or body fails
+// with [service.ErrAlreadyExists], as does a request whose key is used by a
+// request that is still in progress. Responses with a 5xx, 499 or 408 status
+// code are not recorded, nor are the requests whose handler panics, so that the
+// request can be retried.
+func (k *Keys) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		key := r.Header.Get(Header)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Read the body in order to fingerprint the request, and restore it
+		// for the next handler.
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, validation.MaxBodyBytes))
+		if err != nil {
+			problem.Write(ctx, w, validation.BodyError(err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		rec, err := k.reserve(ctx, scope(r, key), key, fingerprint(r, body))
+		if err != nil {
+			problem.Write(ctx, w, err)
+			return
+		}
+		if rec.Completed {
+			slog.Info("replaying recorded response", slog.String("key", key))
+			replay(w, rec)
+			return
+		}
+
+		// Execute the request and record its response. The response is
+		// recorded even if the request context was cancelled in the
+		// meantime, e.g. because the client went away, since the handler
+		// may have committed its changes regardless. The key is released if
+		// the handler panics.
+		rw := &recorder{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			if !completed {
+				k.release(context.WithoutCancel(ctx), rec)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+		completed = true
+		k.record(context.WithoutCancel(ctx), rec, rw)
+	})
+}
+
+// scope returns the id of the record for the given idempotency key, which is
+// scoped to the caller of the request. The subject is escaped, so that the
+// first slash of the id separates it from the key.
+func scope(r *http.Request, key string) string {
+	var subject string
+	if p, ok := auth.FromContext(r.Context()); ok {
+		subject = p.Subject
+	}
+	return url.PathEscape(subject) + "/" + key
+}
+
+// reserve reserves the record with the given id for the request with the given
+// fingerprint and returns that record. If the key was already used for the
+// same request, then the existing record is returned. This function returns
+// [service.ErrAlreadyExists] if the key is used for a different request, or
+// if the request is still in progress.
+func (k *Keys) reserve(
+	ctx context.Context,
+	id, key, fingerprint string,
+) (*IdempotencyRecord, error) {
+	now := time.Now().UTC()
+	rec := IdempotencyRecord{
+		ID:          id,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(k.ttl),
+	}
+
+	// Try to reserve the key twice. The second attempt is made only if the
+	// existing record is expired and had to be removed.
+	for attempt := 0; attempt < 2; attempt++ {
+		err := k.bookingsDB.Create(ctx, IdempotencyCollection, rec)
+		if err == nil {
+			return &rec, nil
+		}
+		if !errors.Is(err, service.ErrAlreadyExists) {
+			return nil, fmt.Errorf("create record: %w", err)
+		}
+
+		existing, err := k.get(ctx, id)
+		if errors.Is(err, service.ErrNotFound) {
+			continue // the record expired in the meantime
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !existing.ExpiresAt.Before(now) {
+			return existing, check(existing, key, fingerprint)
+		}
+
+		// The existing record is expired, remove it and try again.
+		err = k.bookingsDB.Delete(ctx, IdempotencyCollection, id)
+		if err != nil && !errors.Is(err, service.ErrNotFound) {
+			return nil, fmt.Errorf("delete expired record: %w", err)
+		}
+	}
+	return nil, fmt.Errorf(
+		"%w: request with idempotency key %q is in progress", service.ErrAlreadyExists, key)
+}
+
+// check returns [service.ErrAlreadyExists] if the existing record cannot be
+// used for replaying the response to the request with the given key and
+// fingerprint.
+func check(existing *IdempotencyRecord, key, fingerprint string) error {
+	if existing.Fingerprint != fingerprint {
+		return fmt.Errorf(
+			"%w: idempotency key %q was used for a different request",
+			service.ErrAlreadyExists, key)
+	}
+	if !existing.Completed {
+		return fmt.Errorf(
+			"%w: request with idempotency key %q is in progress",
+			service.ErrAlreadyExists, key)
+	}
+	return nil
+}
+
+// get retrieves the record with the given id. This function returns
+// [service.ErrNotFound] if there is no such record.
+func (k *Keys) get(ctx context.Context, id string) (*IdempotencyRecord, error) {
+	elem, err := k.bookingsDB.GetByID(ctx, IdempotencyCollection, id)
+	if err != nil {
+		return nil, fmt.Errorf("get record: %w", err)
+	}
+	rec, ok := elem.(IdempotencyRecord)
+	if !ok {
+		return nil, service.Unexpected(ctx, fmt.Errorf("invalid record type %T", elem))
+	}
+	return &rec, nil
+}
+
+// record stores the response of the request in the record of its key. If the
+// request failed in a way that the client is expected to retry, then the key
+// is released instead.
+func (k *Keys) record(ctx context.Context, rec *IdempotencyRecord, rw *recorder) {
+	if retryable(rw.status) {
+		k.release(ctx, rec)
+		return
+	}
+
+	rec.Completed = true
+	rec.StatusCode = rw.status
+	rec.Header = map[string][]string{
+		"Content-Type": rw.Header().Values("Content-Type"),
+		"Location":     rw.Header().Values("Location"),
+	}
+	rec.Body = rw.body.Bytes()
+	if err := k.bookingsDB.Replace(ctx, IdempotencyCollection, *rec); err != nil {
+		slog.Error(
+			"failed to record response",
+			slog.String("key", rec.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// release removes the record of the key, so that the request can be retried.
+func (k *Keys) release(ctx context.Context, rec *IdempotencyRecord) {
+	err := k.bookingsDB.Delete(ctx, IdempotencyCollection, rec.ID)
+	if err != nil && !errors.Is(err, service.ErrNotFound) {
+		slog.Error(
+			"failed to release idempotency key",
+			slog.String("key", rec.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// retryable returns true if a response with the given status code must not be
+// replayed, since the request did not complete and the client is expected to
+// retry it.
+func retryable(status int) bool {
+	return status >= http.StatusInternalServerError ||
+		status == service.StatusClientClosedConnection ||
+		status == http.StatusRequestTimeout
+}
+
+// fingerprint identifies the request by its caller, method, path and body. The
+// caller is part of the fingerprint, so that a user cannot replay the response
+// recorded for another user.
+func fingerprint(r *http.Request, body []byte) string {
+	line := r.Method + " " + r.URL.Path
+	if p, ok := auth.FromContext(r.Context()); ok {
+		line = p.Subject + " " + line
+	}
+	data := append([]byte(line+"\n"), body...)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// replay writes the recorded response.
+func replay(w http.ResponseWriter, rec *IdempotencyRecord) {
+	for name, values := range rec.Header {
+		for _, v := range values {
+			w.Header().Add(name, v)
+		}
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(rec.StatusCode)
+	if _, err := w.Write(rec.Body); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+// recorder is an [http.ResponseWriter] which records the status code and the
+// body written to the underlying writer.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader implements the [http.ResponseWriter] interface.
+func (rw *recorder) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements the [http.ResponseWriter] interface.
+func (rw *recorder) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b) //nolint:wrapcheck // the error is passed through
+}
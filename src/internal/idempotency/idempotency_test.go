@@ -0,0 +1,147 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eventscompass/booking-service/src/internal/auth"
+	"github.com/eventscompass/booking-service/src/internal/memory"
+	"github.com/eventscompass/service-framework/service"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		status     int
+		interrupt  bool
+		wantCalls  int
+		wantStatus int
+	}{
+		"Completed":   {status: http.StatusCreated, wantCalls: 1, wantStatus: http.StatusCreated},
+		"ClientError": {status: http.StatusNotFound, wantCalls: 1, wantStatus: http.StatusNotFound},
+		"ServerError": {
+			status:     http.StatusInternalServerError,
+			wantCalls:  2,
+			wantStatus: http.StatusInternalServerError,
+		},
+		"ClientClosed": {
+			status:     service.StatusClientClosedConnection,
+			wantCalls:  2,
+			wantStatus: service.StatusClientClosedConnection,
+		},
+		"RequestTimeout": {
+			status:     http.StatusRequestTimeout,
+			wantCalls:  2,
+			wantStatus: http.StatusRequestTimeout,
+		},
+		"Interrupted": {
+			status:     http.StatusCreated,
+			interrupt:  true,
+			wantCalls:  1,
+			wantStatus: http.StatusCreated,
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			db := memory.NewMemoryContainer()
+			t.Cleanup(func() { _ = db.Close() })
+			keys := NewKeys(db, time.Hour)
+
+			var calls int
+			var cancel context.CancelFunc
+			h := keys.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if test.interrupt {
+					cancel()
+				}
+				w.WriteHeader(test.status)
+			}))
+
+			// Send the same request twice. The second one is executed
+			// only if the key was released after the first one.
+			for i := 0; i < 2; i++ {
+				var ctx context.Context
+				ctx, cancel = context.WithCancel(context.Background())
+				r := httptest.NewRequest(http.MethodPost, "/api/bookings",
+					strings.NewReader(`{"user_id":"u1","event_id":"e1"}`)).WithContext(ctx)
+				r.Header.Set(Header, "key-1")
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				cancel()
+				if w.Code != test.wantStatus {
+					t.Fatalf("request %d: want status %d, got %d", i, test.wantStatus, w.Code)
+				}
+			}
+			if calls != test.wantCalls {
+				t.Errorf("want %d calls of the handler, got %d", test.wantCalls, calls)
+			}
+		})
+	}
+}
+
+// send sends a request to create a booking with the key "key-1", on behalf of
+// the given caller if not nil. It returns the status of the response.
+func send(h http.Handler, caller *auth.Principal) int {
+	r := httptest.NewRequest(http.MethodPost, "/api/bookings",
+		strings.NewReader(`{"user_id":"u1","event_id":"e1"}`))
+	if caller != nil {
+		r = r.WithContext(auth.WithPrincipal(r.Context(), caller))
+	}
+	r.Header.Set(Header, "key-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	db := memory.NewMemoryContainer()
+	t.Cleanup(func() { _ = db.Close() })
+	keys := NewKeys(db, time.Hour)
+
+	var calls int
+	h := keys.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("handler failed")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("want the panic to be passed through")
+			}
+		}()
+		send(h, nil)
+	}()
+	if status := send(h, nil); status != http.StatusCreated || calls != 2 {
+		t.Errorf("want the retry to be executed, got status %d after %d calls", status, calls)
+	}
+}
+
+func TestMiddlewareScopesKeysToCallers(t *testing.T) {
+	db := memory.NewMemoryContainer()
+	t.Cleanup(func() { _ = db.Close() })
+	keys := NewKeys(db, time.Hour)
+
+	var calls int
+	h := keys.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	callers := []*auth.Principal{{Subject: "u1"}, {Subject: "u2"}, {Subject: "u1"}, nil}
+	for _, caller := range callers {
+		if status := send(h, caller); status != http.StatusCreated {
+			t.Fatalf("caller %+v: want status %d, got %d", caller, http.StatusCreated, status)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("want the handler to be called once per caller, got %d calls", calls)
+	}
+}
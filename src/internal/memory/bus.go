@@ -0,0 +1,204 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+// Bus is a message bus which delivers messages in-process. It is meant to be
+// used for tests and for single-node deployments. Topics are routed like in an
+// AMQP topic exchange: subscriptions can use the wildcards "*", which matches
+// exactly one word, and "#", which matches zero or more words, where words are
+// separated by dots. All published messages are recorded, so that tests can
+// assert on them. It is safe for concurrent use.
+type Bus struct {
+	// mu guards all fields below.
+	mu sync.Mutex
+
+	// subs are the active subscriptions.
+	subs map[*subscription]struct{}
+
+	// published records all the published messages, in order.
+	published []Message
+
+	// closed is closed once the bus is closed.
+	closed chan struct{}
+}
+
+var _ service.MessageBus = (*Bus)(nil)
+
+// Message is a message published on the [Bus].
+type Message struct {
+	Topic string
+	Body  []byte
+}
+
+// subscription is a subscription for the topics matching a pattern. Messages
+// are queued until the subscriber handles them, thus publishing never blocks
+// on slow subscribers.
+type subscription struct {
+	pattern string
+
+	// mu guards the queue.
+	mu    sync.Mutex
+	queue [][]byte
+
+	// ready signals that the queue is not empty.
+	ready chan struct{}
+}
+
+// NewBus creates a new [Bus] instance.
+func NewBus() *Bus {
+	return &Bus{
+		subs:   make(map[*subscription]struct{}),
+		closed: make(chan struct{}),
+	}
+}
+
+// Publish publishes a message to a given topic. The message is delivered to
+// all the subscriptions whose pattern matches the topic. This function returns
+// [service.ErrConnectionClosed] if the bus is closed.
+func (b *Bus) Publish(_ context.Context, topic string, msg []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.isClosed() {
+		return fmt.Errorf("%w: bus is closed", service.ErrConnectionClosed)
+	}
+
+	body := slices.Clone(msg)
+	b.published = append(b.published, Message{Topic: topic, Body: body})
+	for sub := range b.subs {
+		if matchTopic(sub.pattern, topic) {
+			sub.push(body)
+		}
+	}
+	return nil
+}
+
+// Subscribe subscribes to the topics matching the given pattern. The event
+// handler callback will be executed on every received message. Messages
+// published before subscribing are not received. This function returns
+// [service.ErrConnectionClosed] if the bus is closed. This is a blocking
+// function. Canceling the context or closing the bus will cancel the
+// subscription.
+func (b *Bus) Subscribe(ctx context.Context, pattern string, h service.EventHandler) error {
+	sub := &subscription{
+		pattern: pattern,
+		ready:   make(chan struct{}, 1),
+	}
+
+	b.mu.Lock()
+	if b.isClosed() {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: bus is closed", service.ErrConnectionClosed)
+	}
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.subs, sub)
+		b.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-b.closed:
+			return nil
+		case <-sub.ready:
+		}
+		for _, msg := range sub.drain() {
+			h(ctx, msg)
+		}
+	}
+}
+
+// Published returns the messages that were published on the bus, in order.
+func (b *Bus) Published() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.published)
+}
+
+// Connected returns true unless the bus is closed.
+func (b *Bus) Connected() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.isClosed()
+}
+
+// Close closes the bus and cancels all the subscriptions.
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.isClosed() {
+		close(b.closed)
+	}
+	return nil
+}
+
+// isClosed returns true if the bus is closed.
+func (b *Bus) isClosed() bool {
+	select {
+	case <-b.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// push adds the message to the queue of the subscription.
+func (s *subscription) push(msg []byte) {
+	s.mu.Lock()
+	s.queue = append(s.queue, msg)
+	s.mu.Unlock()
+
+	select {
+	case s.ready <- struct{}{}:
+	default: // the subscriber was already signalled
+	}
+}
+
+// drain removes and returns all the queued messages.
+func (s *subscription) drain() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msgs := s.queue
+	s.queue = nil
+	return msgs
+}
+
+// matchTopic returns true if the topic matches the pattern. The pattern can
+// contain the wildcards "*", which matches exactly one word, and "#", which
+// matches zero or more words.
+func matchTopic(pattern, topic string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(topic, "."))
+}
+
+func matchWords(pattern, topic []string) bool {
+	if len(pattern) == 0 {
+		return len(topic) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		// Try to match zero words, then one more word at a time.
+		for i := 0; i <= len(topic); i++ {
+			if matchWords(pattern[1:], topic[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(topic) > 0 && matchWords(pattern[1:], topic[1:])
+	default:
+		return len(topic) > 0 && pattern[0] == topic[0] && matchWords(pattern[1:], topic[1:])
+	}
+}
@@ -0,0 +1,242 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := map[string]struct {
+		pattern string
+		topics  map[string]bool
+	}{
+		"Exact": {
+			pattern: "event.created",
+			topics: map[string]bool{
+				"event.created":    true,
+				"event.updated":    false,
+				"event":            false,
+				"event.created.v2": false,
+				"location.created": false,
+			},
+		},
+		"SingleWord": {
+			pattern: "event.*",
+			topics: map[string]bool{
+				"event.created":    true,
+				"event.updated":    true,
+				"event":            false,
+				"event.created.v2": false,
+				"location.created": false,
+			},
+		},
+		"SingleWordInTheMiddle": {
+			pattern: "event.*.v2",
+			topics: map[string]bool{
+				"event.created.v2":   true,
+				"event.v2":           false,
+				"event.created.v3":   false,
+				"event.a.created.v2": false,
+			},
+		},
+		"SingleWordOnly": {
+			pattern: "*",
+			topics: map[string]bool{
+				"event":         true,
+				"event.created": false,
+			},
+		},
+		"MultipleWords": {
+			pattern: "event.#",
+			topics: map[string]bool{
+				"event":            true,
+				"event.created":    true,
+				"event.created.v2": true,
+				"location.created": false,
+				"events.created":   false,
+			},
+		},
+		"MultipleWordsInTheMiddle": {
+			pattern: "event.#.v2",
+			topics: map[string]bool{
+				"event.v2":                   true,
+				"event.created.v2":           true,
+				"event.created.cancelled.v2": true,
+				"event.created.v3":           false,
+				"event.v2.created":           false,
+			},
+		},
+		"MultipleWordsFirst": {
+			pattern: "#.created",
+			topics: map[string]bool{
+				"created":          true,
+				"event.created":    true,
+				"location.created": true,
+				"event.created.v2": false,
+			},
+		},
+		"MultipleWordsOnly": {
+			pattern: "#",
+			topics: map[string]bool{
+				"event":            true,
+				"event.created":    true,
+				"event.created.v2": true,
+			},
+		},
+		"BothWildcards": {
+			pattern: "*.*.#",
+			topics: map[string]bool{
+				"event.created":    true,
+				"event.created.v2": true,
+				"event":            false,
+			},
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			for topic, want := range test.topics {
+				if got := matchTopic(test.pattern, topic); got != want {
+					t.Errorf("match %q against %q: want %t, got %t", topic, test.pattern, want, got)
+				}
+			}
+		})
+	}
+}
+
+// subscribe subscribes to the pattern in the background, and returns a function
+// which waits for the given number of messages and returns them sorted.
+func subscribe(
+	ctx context.Context,
+	t *testing.T,
+	b *Bus,
+	pattern string,
+) func(n int) []string {
+	t.Helper()
+	subs := subscriptions(b)
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := b.Subscribe(ctx, pattern, func(_ context.Context, msg []byte) {
+			mu.Lock()
+			received = append(received, string(msg))
+			mu.Unlock()
+		})
+		if err != nil {
+			t.Errorf("subscribe to %q: %v", pattern, err)
+		}
+	}()
+	t.Cleanup(func() { <-done })
+
+	// Messages published before subscribing are not received, thus wait
+	// for the subscription to be registered.
+	for subscriptions(b) == subs {
+		time.Sleep(time.Millisecond)
+	}
+
+	return func(n int) []string {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			mu.Lock()
+			got := append([]string(nil), received...)
+			mu.Unlock()
+			if len(got) >= n || time.Now().After(deadline) {
+				sort.Strings(got)
+				return got
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// subscriptions returns the number of active subscriptions of the bus.
+func subscriptions(b *Bus) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func TestBusRouting(t *testing.T) {
+	// The subscriptions are waited for on cleanup, thus they must be
+	// cancelled before.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := NewBus()
+
+	exact := subscribe(ctx, t, b, "event.created")
+	single := subscribe(ctx, t, b, "event.*")
+	multi := subscribe(ctx, t, b, "#")
+	none := subscribe(ctx, t, b, "user.*")
+
+	for _, topic := range []string{"event.created", "event.updated", "location.created"} {
+		if err := b.Publish(ctx, topic, []byte(topic)); err != nil {
+			t.Fatalf("publish to %q: %v", topic, err)
+		}
+	}
+
+	tests := map[string]struct {
+		received func(int) []string
+		want     []string
+	}{
+		"Exact":  {exact, []string{"event.created"}},
+		"Single": {single, []string{"event.created", "event.updated"}},
+		"Multi":  {multi, []string{"event.created", "event.updated", "location.created"}},
+		"None":   {none, nil},
+	}
+	for name, test := range tests {
+		got := test.received(len(test.want))
+		if len(got) != len(test.want) {
+			t.Errorf("%s: want messages %v, got %v", name, test.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%s: want messages %v, got %v", name, test.want, got)
+				break
+			}
+		}
+	}
+
+	if n := len(b.Published()); n != 3 {
+		t.Errorf("want 3 recorded messages, got %d", n)
+	}
+}
+
+func TestBusClose(t *testing.T) {
+	b := NewBus()
+	done := make(chan error, 1)
+	go func() {
+		done <- b.Subscribe(context.Background(), "#", func(context.Context, []byte) {})
+	}()
+	for subscriptions(b) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Closing the bus cancels the subscriptions, and publishing fails.
+	if err := b.Close(); err != nil {
+		t.Fatalf("close bus: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("want the subscription to end without an error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("want the subscription to end")
+	}
+	err := b.Publish(context.Background(), "event.created", nil)
+	if !errors.Is(err, service.ErrConnectionClosed) {
+		t.Errorf("want error %v, got %v", service.ErrConnectionClosed, err)
+	}
+}
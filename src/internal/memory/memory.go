@@ -0,0 +1,519 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// MemoryContainer is a container which keeps all entries in memory. It is
+// meant to be used for tests and for local development, and provides the same
+// semantics as the containers backed by a database. It is safe for concurrent
+// use.
+//
+//nolint:revive // this type will probably not be used as memory.MemoryContainer
+type MemoryContainer struct {
+	// mu guards the collections. It is held for the whole duration
+	// of a transaction, thus transactions are serializable.
+	mu sync.Mutex
+
+	// collections maps the name of every collection to its entries,
+	// indexed by their id.
+	collections map[string]map[string]any
+}
+
+var (
+	_ BookingsContainer = (*MemoryContainer)(nil)
+	_ io.Closer         = (*MemoryContainer)(nil)
+)
+
+// NewMemoryContainer creates a new empty [MemoryContainer] instance.
+func NewMemoryContainer() *MemoryContainer {
+	return &MemoryContainer{
+		collections: make(map[string]map[string]any),
+	}
+}
+
+// txKey is the context key marking that the context belongs to a transaction
+// of the container stored as value.
+type txKey struct{}
+
+// lock locks the container, unless the context belongs to a transaction of
+// the container, in which case the lock is already held. The returned function
+// must be called to release the lock.
+func (m *MemoryContainer) lock(ctx context.Context) func() {
+	if tx, ok := ctx.Value(txKey{}).(*MemoryContainer); ok && tx == m {
+		return func() {}
+	}
+	m.mu.Lock()
+	return m.mu.Unlock
+}
+
+// collection returns the entries of the given collection. This function
+// returns [service.ErrNotAllowed] if the collection is not in the container.
+func (m *MemoryContainer) collection(name string) (map[string]any, error) {
+	if !KnownCollection(name) {
+		return nil, fmt.Errorf("%w: unknown collection %q", service.ErrNotAllowed, name)
+	}
+	c, ok := m.collections[name]
+	if !ok {
+		c = make(map[string]any)
+		m.collections[name] = c
+	}
+	return c, nil
+}
+
+// Ping implements the [BookingsContainer] interface.
+func (m *MemoryContainer) Ping(context.Context) error {
+	return nil
+}
+
+// Create implements the [BookingsContainer] interface.
+func (m *MemoryContainer) Create(ctx context.Context, collection string, data any) error {
+	defer m.lock(ctx)()
+
+	c, err := m.collection(collection)
+	if err != nil {
+		return err
+	}
+	id, err := entryID(data)
+	if err != nil {
+		return err
+	}
+	if _, ok := c[id]; ok {
+		return fmt.Errorf("%w: %q in %q", service.ErrAlreadyExists, id, collection)
+	}
+
+	// A user can be waitlisted only once for the same event.
+	if e, ok := data.(WaitlistEntry); ok {
+		for _, elem := range c {
+			if other, ok := elem.(WaitlistEntry); ok &&
+				other.EventID == e.EventID && other.UserID == e.UserID {
+				return fmt.Errorf("%w: user %q is already waitlisted for event %q",
+					service.ErrAlreadyExists, e.UserID, e.EventID)
+			}
+		}
+	}
+	c[id] = data
+	return nil
+}
+
+// entryID returns the id of the given entry. This function returns
+// [service.ErrBadRequest] if the entry is not of a type that can be stored in
+// the container.
+func entryID(data any) (string, error) {
+	switch v := data.(type) {
+	case Booking:
+		return v.ID, nil
+	case Event:
+		return v.ID, nil
+	case Location:
+		return v.ID, nil
+	case User:
+		return v.ID, nil
+	case WaitlistEntry:
+		return v.ID, nil
+	case OutboxMessage:
+		return v.ID, nil
+	case IdempotencyRecord:
+		return v.ID, nil
+	case DeadLetter:
+		return v.ID, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported entry type %T", service.ErrBadRequest, data)
+	}
+}
+
+// GetByID implements the [BookingsContainer] interface.
+func (m *MemoryContainer) GetByID(ctx context.Context, collection string, id string) (any, error) {
+	defer m.lock(ctx)()
+
+	c, err := m.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	elem, ok := c[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q in %q", service.ErrNotFound, id, collection)
+	}
+	return elem, nil
+}
+
+// Replace implements the [BookingsContainer] interface.
+func (m *MemoryContainer) Replace(ctx context.Context, collection string, data any) error {
+	defer m.lock(ctx)()
+
+	c, err := m.collection(collection)
+	if err != nil {
+		return err
+	}
+	id, err := entryID(data)
+	if err != nil {
+		return err
+	}
+	if _, ok := c[id]; !ok {
+		return fmt.Errorf("%w: %q in %q", service.ErrNotFound, id, collection)
+	}
+	c[id] = data
+	return nil
+}
+
+// UpsertEvent implements the [BookingsContainer] interface.
+func (m *MemoryContainer) UpsertEvent(ctx context.Context, e Event) error {
+	defer m.lock(ctx)()
+
+	c, err := m.collection(EventsCollection)
+	if err != nil {
+		return err
+	}
+	e.Booked, e.Cancelled = 0, false
+	if stored, ok := c[e.ID].(Event); ok {
+		if e.Version != 0 && stored.Version >= e.Version {
+			return fmt.Errorf("%w: event %q has version %d",
+				service.ErrAlreadyExists, e.ID, stored.Version)
+		}
+		e.Version = max(e.Version, stored.Version)
+		e.Booked = stored.Booked
+		e.Cancelled = stored.Cancelled
+	}
+	c[e.ID] = e
+	return nil
+}
+
+// UpsertLocation implements the [BookingsContainer] interface.
+func (m *MemoryContainer) UpsertLocation(ctx context.Context, l Location) error {
+	defer m.lock(ctx)()
+
+	c, err := m.collection(LocationsCollection)
+	if err != nil {
+		return err
+	}
+	if stored, ok := c[l.ID].(Location); ok {
+		if l.Version != 0 && stored.Version >= l.Version {
+			return fmt.Errorf("%w: location %q has version %d",
+				service.ErrAlreadyExists, l.ID, stored.Version)
+		}
+		l.Version = max(l.Version, stored.Version)
+	}
+	c[l.ID] = l
+	return nil
+}
+
+// Delete implements the [BookingsContainer] interface.
+func (m *MemoryContainer) Delete(ctx context.Context, collection string, id string) error {
+	defer m.lock(ctx)()
+
+	c, err := m.collection(collection)
+	if err != nil {
+		return err
+	}
+	if _, ok := c[id]; !ok {
+		return fmt.Errorf("%w: %q in %q", service.ErrNotFound, id, collection)
+	}
+	delete(c, id)
+	return nil
+}
+
+// SetCapacity implements the [BookingsContainer] interface.
+func (m *MemoryContainer) SetCapacity(
+	ctx context.Context,
+	collection string,
+	id string,
+	capacity int,
+) error {
+	defer m.lock(ctx)()
+
+	switch collection {
+	case EventsCollection:
+		return update(m, collection, id, func(e *Event) error {
+			e.Capacity = capacity
+			return nil
+		})
+	case LocationsCollection:
+		return update(m, collection, id, func(l *Location) error {
+			l.Capacity = capacity
+			return nil
+		})
+	default:
+		return fmt.Errorf(
+			"%w: collection %q has no capacity", service.ErrNotAllowed, collection)
+	}
+}
+
+// CancelEvent implements the [BookingsContainer] interface.
+func (m *MemoryContainer) CancelEvent(ctx context.Context, id string) error {
+	defer m.lock(ctx)()
+
+	c, err := m.collection(EventsCollection)
+	if err != nil {
+		return err
+	}
+	e, _ := c[id].(Event) //nolint:errcheck // a missing event is created
+	e.ID = id
+	e.Cancelled = true
+	c[id] = e
+	return nil
+}
+
+// ReserveSeats implements the [BookingsContainer] interface.
+func (m *MemoryContainer) ReserveSeats(ctx context.Context, eventID string, seats int) error {
+	defer m.lock(ctx)()
+
+	return update(m, EventsCollection, eventID, func(e *Event) error {
+		if e.Cancelled {
+			return fmt.Errorf("%w: event %q is cancelled", service.ErrNotAllowed, eventID)
+		}
+		if e.Capacity > 0 && e.Booked+seats > e.Capacity {
+			return fmt.Errorf("%w: event %q is sold out", service.ErrSpaceFull, eventID)
+		}
+		e.Booked += seats
+		return nil
+	})
+}
+
+// ReleaseSeats implements the [BookingsContainer] interface.
+func (m *MemoryContainer) ReleaseSeats(ctx context.Context, eventID string, seats int) error {
+	defer m.lock(ctx)()
+
+	return update(m, EventsCollection, eventID, func(e *Event) error {
+		if e.Booked < seats {
+			return fmt.Errorf(
+				"%w: event %q has no booked seats", service.ErrUnexpected, eventID)
+		}
+		e.Booked -= seats
+		return nil
+	})
+}
+
+// UpdateStatus implements the [BookingsContainer] interface.
+func (m *MemoryContainer) UpdateStatus(
+	ctx context.Context,
+	id string,
+	from BookingStatus,
+	to BookingStatus,
+) error {
+	defer m.lock(ctx)()
+
+	return update(m, BookingsCollection, id, func(b *Booking) error {
+		if b.Status != from {
+			return fmt.Errorf("%w: booking %q is not %s", service.ErrNotAllowed, id, from)
+		}
+		b.Status = to
+		b.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+}
+
+// update applies fn to the entry with the given id from the given collection.
+// The entry is changed only if fn succeeds. The lock must be held by the
+// caller. This function returns [service.ErrNotFound] if the entry is not in
+// the collection.
+func update[T any](m *MemoryContainer, collection, id string, fn func(*T) error) error {
+	c, err := m.collection(collection)
+	if err != nil {
+		return err
+	}
+	elem, ok := c[id]
+	if !ok {
+		return fmt.Errorf("%w: %q in %q", service.ErrNotFound, id, collection)
+	}
+	v, ok := elem.(T)
+	if !ok {
+		return fmt.Errorf("%w: invalid entry type %T", service.ErrUnexpected, elem)
+	}
+	if err := fn(&v); err != nil {
+		return err
+	}
+	c[id] = v
+	return nil
+}
+
+// entries returns the entries of type T from the given collection that match
+// the filter. The lock must be held by the caller.
+func entries[T any](m *MemoryContainer, collection string, filter func(*T) bool) []T {
+	var res []T
+	for _, elem := range m.collections[collection] {
+		if v, ok := elem.(T); ok && filter(&v) {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// limited returns the first limit entries of the given slice. A zero limit
+// means no limit.
+func limited[T any](entries []T, limit int) []T {
+	if limit == 0 {
+		return entries
+	}
+	return entries[:min(len(entries), limit)]
+}
+
+// ListBookings implements the [BookingsContainer] interface.
+func (m *MemoryContainer) ListBookings(ctx context.Context, f *BookingFilter) ([]Booking, error) {
+	defer m.lock(ctx)()
+
+	bookings := entries(m, BookingsCollection, func(b *Booking) bool {
+		switch {
+		case f.UserID != "" && b.UserID != f.UserID,
+			f.EventID != "" && b.EventID != f.EventID,
+			f.Status != "" && b.Status != f.Status,
+			!f.From.IsZero() && b.Date.Before(f.From),
+			!f.To.IsZero() && !b.Date.Before(f.To):
+			return false
+		case f.AfterID != "":
+			return b.Date.After(f.AfterDate) ||
+				(b.Date.Equal(f.AfterDate) && b.ID > f.AfterID)
+		default:
+			return true
+		}
+	})
+	slices.SortFunc(bookings, func(a, b Booking) int {
+		if c := a.Date.Compare(b.Date); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return limited(bookings, f.Limit), nil
+}
+
+// ExpiredHolds implements the [BookingsContainer] interface.
+func (m *MemoryContainer) ExpiredHolds(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]Booking, error) {
+	defer m.lock(ctx)()
+
+	holds := entries(m, BookingsCollection, func(b *Booking) bool {
+		return b.Status == StatusPending && b.ExpiresAt != nil && !b.ExpiresAt.After(before)
+	})
+	slices.SortFunc(holds, func(a, b Booking) int {
+		return a.ExpiresAt.Compare(*b.ExpiresAt)
+	})
+	return limited(holds, limit), nil
+}
+
+// Waitlist implements the [BookingsContainer] interface.
+func (m *MemoryContainer) Waitlist(ctx context.Context, eventID string) ([]WaitlistEntry, error) {
+	defer m.lock(ctx)()
+
+	waitlist := entries(m, WaitlistCollection, func(e *WaitlistEntry) bool {
+		return e.EventID == eventID
+	})
+	slices.SortFunc(waitlist, func(a, b WaitlistEntry) int {
+		if c := a.JoinedAt.Compare(b.JoinedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return waitlist, nil
+}
+
+// WithTransaction implements the [BookingsContainer] interface.
+func (m *MemoryContainer) WithTransaction(
+	ctx context.Context,
+	fn func(context.Context) error,
+) error {
+	if tx, ok := ctx.Value(txKey{}).(*MemoryContainer); ok && tx == m {
+		return fn(ctx) // nested transactions are part of the outer one
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Take a snapshot of the collections, so that the changes made by fn can
+	// be rolled back. Entries are stored by value, thus a shallow copy of
+	// every collection is enough.
+	snapshot := make(map[string]map[string]any, len(m.collections))
+	for name, c := range m.collections {
+		snapshot[name] = maps.Clone(c)
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, m)); err != nil {
+		m.collections = snapshot
+		return err
+	}
+	return nil
+}
+
+// PendingMessages implements the [BookingsContainer] interface.
+func (m *MemoryContainer) PendingMessages(ctx context.Context, limit int) ([]OutboxMessage, error) {
+	defer m.lock(ctx)()
+
+	msgs := entries(m, OutboxCollection, func(msg *OutboxMessage) bool {
+		return !msg.Sent && !msg.Parked
+	})
+	slices.SortFunc(msgs, func(a, b OutboxMessage) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return limited(msgs, limit), nil
+}
+
+// DeadLetters implements the [BookingsContainer] interface.
+func (m *MemoryContainer) DeadLetters(ctx context.Context, limit int) ([]DeadLetter, error) {
+	defer m.lock(ctx)()
+
+	msgs := entries(m, DeadLettersCollection, func(*DeadLetter) bool { return true })
+	slices.SortFunc(msgs, func(a, b DeadLetter) int {
+		if c := a.FailedAt.Compare(b.FailedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return limited(msgs, limit), nil
+}
+
+// RecordAttempt implements the [BookingsContainer] interface.
+func (m *MemoryContainer) RecordAttempt(ctx context.Context, id string, sendErr error) error {
+	defer m.lock(ctx)()
+
+	return update(m, OutboxCollection, id, func(msg *OutboxMessage) error {
+		msg.Attempts++
+		if sendErr != nil {
+			msg.LastError = sendErr.Error()
+			return nil
+		}
+		msg.Sent = true
+		msg.SentAt = time.Now().UTC()
+		return nil
+	})
+}
+
+// ParkMessage implements the [BookingsContainer] interface.
+func (m *MemoryContainer) ParkMessage(ctx context.Context, id string) error {
+	defer m.lock(ctx)()
+
+	return update(m, OutboxCollection, id, func(msg *OutboxMessage) error {
+		msg.Parked = true
+		msg.ParkedAt = time.Now().UTC()
+		return nil
+	})
+}
+
+// PurgeMessages implements the [BookingsContainer] interface.
+func (m *MemoryContainer) PurgeMessages(ctx context.Context, before time.Time) (int, error) {
+	defer m.lock(ctx)()
+
+	msgs := entries(m, OutboxCollection, func(msg *OutboxMessage) bool {
+		return (msg.Sent && msg.SentAt.Before(before)) ||
+			(msg.Parked && msg.ParkedAt.Before(before))
+	})
+	for _, msg := range msgs {
+		delete(m.collections[OutboxCollection], msg.ID)
+	}
+	return len(msgs), nil
+}
+
+// Close implements the [io.Closer] interface.
+func (m *MemoryContainer) Close() error {
+	return nil
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/containertest"
+)
+
+func TestConformance(t *testing.T) {
+	containertest.Run(t, func(t *testing.T) BookingsContainer {
+		return NewMemoryContainer()
+	})
+}
@@ -0,0 +1,88 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// indexes lists for every collection the indexes that back the queries
+// performed by the container. Every key ends with the fields by which the
+// results are sorted, so that the sort can be served by the index.
+var indexes = map[string][]mongo.IndexModel{
+	BookingsCollection: {
+		// Listing bookings, optionally filtered by user, event or status.
+		{Keys: bson.D{{Key: "date", Value: 1}, {Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "userid", Value: 1}, {Key: "date", Value: 1}, {Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "eventid", Value: 1}, {Key: "date", Value: 1}, {Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "date", Value: 1}, {Key: "id", Value: 1}}},
+
+		// Looking up expired holds.
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expiresat", Value: 1}}},
+	},
+	OutboxCollection: {
+		// Looking up pending messages, and purging sent and parked ones.
+		{Keys: bson.D{{Key: "sent", Value: 1}, {Key: "createdat", Value: 1}}},
+		{Keys: bson.D{{Key: "sent", Value: 1}, {Key: "sentat", Value: 1}}},
+		{Keys: bson.D{{Key: "parked", Value: 1}, {Key: "parkedat", Value: 1}}},
+	},
+	IdempotencyCollection: {
+		// Removing expired records. The records are removed by a background
+		// task, which runs every minute, thus they might outlive their expiry.
+		{
+			Keys:    bson.D{{Key: "expiresat", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	},
+	DeadLettersCollection: {
+		// Listing dead-lettered messages.
+		{Keys: bson.D{{Key: "failedat", Value: 1}, {Key: "id", Value: 1}}},
+	},
+	WaitlistCollection: {
+		// Looking up the waitlist of an event.
+		{Keys: bson.D{{Key: "eventid", Value: 1}, {Key: "joinedat", Value: 1}, {Key: "id", Value: 1}}},
+
+		// Waitlisting a user only once for the same event.
+		{
+			Keys:    bson.D{{Key: "eventid", Value: 1}, {Key: "userid", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	},
+}
+
+// collections lists all the collections in the container. Every collection
+// has a unique index on the id of its entries.
+var collections = []string{
+	BookingsCollection,
+	EventsCollection,
+	LocationsCollection,
+	UsersCollection,
+	WaitlistCollection,
+	OutboxCollection,
+	IdempotencyCollection,
+	DeadLettersCollection,
+}
+
+// createIndexes creates the indexes of all collections. Creating an index
+// that already exists is a no-op, thus it is safe to call this function on
+// every start up.
+func (m *MongoDBContainer) createIndexes(ctx context.Context) error {
+	for _, collection := range collections {
+		unique := mongo.IndexModel{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}
+		models := append([]mongo.IndexModel{unique}, indexes[collection]...)
+		_, err := m.database.Collection(collection).Indexes().CreateMany(ctx, models)
+		if err != nil {
+			return service.Unexpected(ctx, fmt.Errorf("create %q indexes: %w", collection, err))
+		}
+	}
+	return nil
+}
@@ -60,16 +60,33 @@ func NewMongoDBContainer(ctx context.Context, cfg *Config) (*MongoDBContainer, e
 		return nil, service.Unexpected(ctx, fmt.Errorf("ping mongo: %w", err))
 	}
 
-	database := client.Database(cfg.Database)
-	return &MongoDBContainer{
+	m := &MongoDBContainer{
 		client:   client,
-		database: database,
-	}, nil
+		database: client.Database(cfg.Database),
+	}
+	if err := m.createIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("create indexes: %w", err)
+	}
+	return m, nil
+}
+
+// Ping implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) Ping(ctx context.Context) error {
+	if err := m.client.Ping(ctx, readpref.Primary()); err != nil {
+		return fmt.Errorf("%w: ping: %v", service.ErrConnectionClosed, err)
+	}
+	return nil
 }
 
 // Create implements the [BookingsContainer] interface.
 func (m *MongoDBContainer) Create(ctx context.Context, collection string, data any) error {
+	if !KnownCollection(collection) {
+		return fmt.Errorf("%w: unknown collection %q", service.ErrNotAllowed, collection)
+	}
 	_, err := m.database.Collection(collection).InsertOne(ctx, data)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: duplicate key in %q", service.ErrAlreadyExists, collection)
+	}
 	if err != nil {
 		return service.Unexpected(ctx, fmt.Errorf("insert one: %w", err))
 	}
@@ -82,11 +99,14 @@ func (m *MongoDBContainer) GetByID(
 	collection string,
 	id string,
 ) (any, error) {
+	if !KnownCollection(collection) {
+		return nil, fmt.Errorf("%w: unknown collection %q", service.ErrNotAllowed, collection)
+	}
 	c := m.database.Collection(collection)
 	one := c.FindOne(ctx, bson.M{"id": id})
 	if err := one.Err(); err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, fmt.Errorf("%w: %v", service.ErrNotFound, err)
+			return nil, fmt.Errorf("%w: %q in %q", service.ErrNotFound, id, collection)
 		}
 		return nil, service.Unexpected(ctx, fmt.Errorf("find one: %w", err))
 	}
@@ -94,17 +114,472 @@ func (m *MongoDBContainer) GetByID(
 	// Infer the type of the requested element and decode it.
 	switch collection {
 	case BookingsCollection:
-		var elem Booking
-		if err := one.Decode(&elem); err != nil {
-			return nil, service.Unexpected(ctx, fmt.Errorf("decode one: %w", err))
-		}
-		return elem, nil
+		return decode[Booking](ctx, one)
+	case EventsCollection:
+		return decode[Event](ctx, one)
+	case LocationsCollection:
+		return decode[Location](ctx, one)
+	case UsersCollection:
+		return decode[User](ctx, one)
+	case WaitlistCollection:
+		return decode[WaitlistEntry](ctx, one)
+	case OutboxCollection:
+		return decode[OutboxMessage](ctx, one)
+	case IdempotencyCollection:
+		return decode[IdempotencyRecord](ctx, one)
+	case DeadLettersCollection:
+		return decode[DeadLetter](ctx, one)
 	default:
 		return nil, fmt.Errorf(
 			"%w: unknown collection %q", service.ErrNotAllowed, collection)
 	}
 }
 
+// Replace implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) Replace(ctx context.Context, collection string, data any) error {
+	if !KnownCollection(collection) {
+		return fmt.Errorf("%w: unknown collection %q", service.ErrNotAllowed, collection)
+	}
+
+	// Read the id of the entry from its encoded form.
+	raw, err := bson.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("%w: marshal entry: %v", service.ErrBadRequest, err)
+	}
+	id, ok := bson.Raw(raw).Lookup("id").StringValueOK()
+	if !ok {
+		return fmt.Errorf("%w: entry has no id", service.ErrBadRequest)
+	}
+
+	res, err := m.database.Collection(collection).ReplaceOne(ctx, bson.M{"id": id}, raw)
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("replace one: %w", err))
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: %q in %q", service.ErrNotFound, id, collection)
+	}
+	return nil
+}
+
+// Delete implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) Delete(ctx context.Context, collection string, id string) error {
+	if !KnownCollection(collection) {
+		return fmt.Errorf("%w: unknown collection %q", service.ErrNotAllowed, collection)
+	}
+	res, err := m.database.Collection(collection).DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("delete one: %w", err))
+	}
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("%w: %q in %q", service.ErrNotFound, id, collection)
+	}
+	return nil
+}
+
+// UpsertEvent implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) UpsertEvent(ctx context.Context, e Event) error {
+	set := bson.M{
+		"name":       e.Name,
+		"locationid": e.LocationID,
+		"start":      e.Start,
+		"end":        e.End,
+		"capacity":   e.Capacity,
+	}
+	setOnInsert := bson.M{"booked": 0, "cancelled": false}
+	return m.upsert(ctx, EventsCollection, e.ID, e.Version, set, setOnInsert)
+}
+
+// UpsertLocation implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) UpsertLocation(ctx context.Context, l Location) error {
+	set := bson.M{
+		"name":     l.Name,
+		"capacity": l.Capacity,
+	}
+	return m.upsert(ctx, LocationsCollection, l.ID, l.Version, set, bson.M{})
+}
+
+// upsert sets the given fields and the version of the entry with the given
+// id, provided that the stored entry has a lower version. A zero version
+// always updates the entry and keeps its version. If the entry does not exist,
+// then it is created with the fields from set and setOnInsert.
+func (m *MongoDBContainer) upsert(
+	ctx context.Context,
+	collection string,
+	id string,
+	version int64,
+	set bson.M,
+	setOnInsert bson.M,
+) error {
+	// If the stored entry has the same or a newer version, then the filter
+	// does not match and the upsert tries to insert a new entry, which is
+	// rejected by the unique index on the id. Entries that were stored
+	// without a version, e.g. by older releases, are always updated.
+	filter := bson.M{"id": id}
+	if version != 0 {
+		filter["$or"] = bson.A{
+			bson.M{"version": bson.M{"$lt": version}},
+			bson.M{"version": bson.M{"$exists": false}},
+		}
+		set["version"] = version
+	} else {
+		setOnInsert["version"] = 0
+	}
+	update := bson.M{"$set": set, "$setOnInsert": setOnInsert}
+
+	c := m.database.Collection(collection)
+	_, err := c.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %q in %q has version %d or newer",
+			service.ErrAlreadyExists, id, collection, version)
+	}
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("update one: %w", err))
+	}
+	return nil
+}
+
+// SetCapacity implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) SetCapacity(
+	ctx context.Context,
+	collection string,
+	id string,
+	capacity int,
+) error {
+	if collection != EventsCollection && collection != LocationsCollection {
+		return fmt.Errorf(
+			"%w: collection %q has no capacity", service.ErrNotAllowed, collection)
+	}
+
+	update := bson.M{"$set": bson.M{"capacity": capacity}}
+	res, err := m.database.Collection(collection).UpdateOne(ctx, bson.M{"id": id}, update)
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("update one: %w", err))
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: %q in %q", service.ErrNotFound, id, collection)
+	}
+	return nil
+}
+
+// CancelEvent implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) CancelEvent(ctx context.Context, id string) error {
+	update := bson.M{
+		"$set":         bson.M{"cancelled": true},
+		"$setOnInsert": bson.M{"booked": 0, "version": 0},
+	}
+	c := m.database.Collection(EventsCollection)
+	_, err := c.UpdateOne(ctx, bson.M{"id": id}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("update one: %w", err))
+	}
+	return nil
+}
+
+// ReserveSeats implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) ReserveSeats(ctx context.Context, eventID string, seats int) error {
+	// The seats are reserved with a single conditional update, which is
+	// atomic. Concurrent reservations can therefore never overbook the event.
+	// Events without a capacity (zero or missing) are not limited.
+	filter := bson.M{
+		"id":        eventID,
+		"cancelled": bson.M{"$ne": true},
+		"$or": bson.A{
+			bson.M{"capacity": bson.M{"$in": bson.A{0, nil}}},
+			bson.M{"$expr": bson.M{
+				"$lte": bson.A{bson.M{"$add": bson.A{"$booked", seats}}, "$capacity"},
+			}},
+		},
+	}
+	update := bson.M{"$inc": bson.M{"booked": seats}}
+
+	c := m.database.Collection(EventsCollection)
+	res, err := c.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("update one: %w", err))
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	// Nothing matched, either the event is missing, cancelled or sold out.
+	var e Event
+	err = c.FindOne(ctx, bson.M{"id": eventID}).Decode(&e)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("%w: event %q", service.ErrNotFound, eventID)
+	}
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("find one: %w", err))
+	}
+	if e.Cancelled {
+		return fmt.Errorf("%w: event %q is cancelled", service.ErrNotAllowed, eventID)
+	}
+	return fmt.Errorf("%w: event %q is sold out", service.ErrSpaceFull, eventID)
+}
+
+// ReleaseSeats implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) ReleaseSeats(ctx context.Context, eventID string, seats int) error {
+	// Never release more seats than the ones that are booked.
+	filter := bson.M{"id": eventID, "booked": bson.M{"$gte": seats}}
+	update := bson.M{"$inc": bson.M{"booked": -seats}}
+
+	c := m.database.Collection(EventsCollection)
+	res, err := c.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("update one: %w", err))
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	n, err := c.CountDocuments(ctx, bson.M{"id": eventID})
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("count documents: %w", err))
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: event %q", service.ErrNotFound, eventID)
+	}
+	return service.Unexpected(ctx, fmt.Errorf("event %q has no booked seats", eventID))
+}
+
+// UpdateStatus implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) UpdateStatus(
+	ctx context.Context,
+	id string,
+	from BookingStatus,
+	to BookingStatus,
+) error {
+	filter := bson.M{"id": id, "status": from}
+	update := bson.M{"$set": bson.M{"status": to, "updatedat": time.Now().UTC()}}
+
+	c := m.database.Collection(BookingsCollection)
+	res, err := c.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("update one: %w", err))
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	n, err := c.CountDocuments(ctx, bson.M{"id": id})
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("count documents: %w", err))
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: booking %q", service.ErrNotFound, id)
+	}
+	return fmt.Errorf("%w: booking %q is not %s", service.ErrNotAllowed, id, from)
+}
+
+// ListBookings implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) ListBookings(ctx context.Context, f *BookingFilter) ([]Booking, error) {
+	filter := bson.M{}
+	if f.UserID != "" {
+		filter["userid"] = f.UserID
+	}
+	if f.EventID != "" {
+		filter["eventid"] = f.EventID
+	}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+
+	date := bson.M{}
+	if !f.From.IsZero() {
+		date["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		date["$lt"] = f.To
+	}
+	if len(date) > 0 {
+		filter["date"] = date
+	}
+
+	// Continue right after the last listed booking.
+	if f.AfterID != "" {
+		filter["$or"] = bson.A{
+			bson.M{"date": bson.M{"$gt": f.AfterDate}},
+			bson.M{"date": f.AfterDate, "id": bson.M{"$gt": f.AfterID}},
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "date", Value: 1}, {Key: "id", Value: 1}}).
+		SetLimit(int64(f.Limit))
+	cursor, err := m.database.Collection(BookingsCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, service.Unexpected(ctx, fmt.Errorf("find: %w", err))
+	}
+
+	var bookings []Booking
+	if err := cursor.All(ctx, &bookings); err != nil {
+		return nil, service.Unexpected(ctx, fmt.Errorf("decode all: %w", err))
+	}
+	return bookings, nil
+}
+
+// ExpiredHolds implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) ExpiredHolds(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]Booking, error) {
+	filter := bson.M{
+		"status":    StatusPending,
+		"expiresat": bson.M{"$lte": before},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "expiresat", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := m.database.Collection(BookingsCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, service.Unexpected(ctx, fmt.Errorf("find: %w", err))
+	}
+
+	var holds []Booking
+	if err := cursor.All(ctx, &holds); err != nil {
+		return nil, service.Unexpected(ctx, fmt.Errorf("decode all: %w", err))
+	}
+	return holds, nil
+}
+
+// Waitlist implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) Waitlist(ctx context.Context, eventID string) ([]WaitlistEntry, error) {
+	opts := options.Find().SetSort(bson.D{
+		{Key: "joinedat", Value: 1},
+		{Key: "id", Value: 1}, // break ties deterministically
+	})
+	cursor, err := m.database.Collection(WaitlistCollection).
+		Find(ctx, bson.M{"eventid": eventID}, opts)
+	if err != nil {
+		return nil, service.Unexpected(ctx, fmt.Errorf("find: %w", err))
+	}
+
+	var entries []WaitlistEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, service.Unexpected(ctx, fmt.Errorf("decode all: %w", err))
+	}
+	return entries, nil
+}
+
+// WithTransaction implements the [BookingsContainer] interface. Note that
+// transactions are supported only if the Mongo server is a replica set member.
+func (m *MongoDBContainer) WithTransaction(
+	ctx context.Context,
+	fn func(context.Context) error,
+) error {
+	sess, err := m.client.StartSession()
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("start session: %w", err))
+	}
+	defer sess.EndSession(ctx)
+
+	// The session context carries the session, so that every operation
+	// performed with it becomes part of the transaction.
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		return nil, fn(sc)
+	})
+	if err != nil {
+		return fmt.Errorf("transaction: %w", err)
+	}
+	return nil
+}
+
+// PendingMessages implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) PendingMessages(
+	ctx context.Context,
+	limit int,
+) ([]OutboxMessage, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdat", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := m.database.Collection(OutboxCollection).
+		Find(ctx, bson.M{"sent": false, "parked": bson.M{"$ne": true}}, opts)
+	if err != nil {
+		return nil, service.Unexpected(ctx, fmt.Errorf("find: %w", err))
+	}
+
+	var msgs []OutboxMessage
+	if err := cursor.All(ctx, &msgs); err != nil {
+		return nil, service.Unexpected(ctx, fmt.Errorf("decode all: %w", err))
+	}
+	return msgs, nil
+}
+
+// DeadLetters implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) DeadLetters(ctx context.Context, limit int) ([]DeadLetter, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "failedat", Value: 1}, {Key: "id", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := m.database.Collection(DeadLettersCollection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, service.Unexpected(ctx, fmt.Errorf("find: %w", err))
+	}
+
+	var msgs []DeadLetter
+	if err := cursor.All(ctx, &msgs); err != nil {
+		return nil, service.Unexpected(ctx, fmt.Errorf("decode all: %w", err))
+	}
+	return msgs, nil
+}
+
+// RecordAttempt implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) RecordAttempt(ctx context.Context, id string, sendErr error) error {
+	set := bson.M{"sent": true, "sentat": time.Now().UTC()}
+	if sendErr != nil {
+		set = bson.M{"lasterror": sendErr.Error()}
+	}
+	update := bson.M{
+		"$inc": bson.M{"attempts": 1},
+		"$set": set,
+	}
+
+	res, err := m.database.Collection(OutboxCollection).
+		UpdateOne(ctx, bson.M{"id": id}, update)
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("update one: %w", err))
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: message %q", service.ErrNotFound, id)
+	}
+	return nil
+}
+
+// ParkMessage implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) ParkMessage(ctx context.Context, id string) error {
+	update := bson.M{"$set": bson.M{"parked": true, "parkedat": time.Now().UTC()}}
+	res, err := m.database.Collection(OutboxCollection).
+		UpdateOne(ctx, bson.M{"id": id}, update)
+	if err != nil {
+		return service.Unexpected(ctx, fmt.Errorf("update one: %w", err))
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: message %q", service.ErrNotFound, id)
+	}
+	return nil
+}
+
+// PurgeMessages implements the [BookingsContainer] interface.
+func (m *MongoDBContainer) PurgeMessages(ctx context.Context, before time.Time) (int, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"sent": true, "sentat": bson.M{"$lt": before}},
+		bson.M{"parked": true, "parkedat": bson.M{"$lt": before}},
+	}}
+	res, err := m.database.Collection(OutboxCollection).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, service.Unexpected(ctx, fmt.Errorf("delete many: %w", err))
+	}
+	return int(res.DeletedCount), nil
+}
+
+// decode decodes the result of a find query into an element of type T.
+func decode[T any](ctx context.Context, one *mongo.SingleResult) (any, error) {
+	var elem T
+	if err := one.Decode(&elem); err != nil {
+		return nil, service.Unexpected(ctx, fmt.Errorf("decode one: %w", err))
+	}
+	return elem, nil
+}
+
 // Close implements the [io.Closer] interface.
 func (m *MongoDBContainer) Close() error {
 	// Disconnect the client by waiting up to 10 seconds for
@@ -0,0 +1,115 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/containertest"
+)
+
+// testContainer is a container backed by a database of its own, which is
+// dropped when the container is closed. The client is shared by all the
+// containers of a test, and is disconnected once the test is finished.
+type testContainer struct {
+	*MongoDBContainer
+}
+
+// Close drops the database of the container.
+func (c testContainer) Close() error {
+	return c.database.Drop(context.Background()) //nolint:wrapcheck // only used by tests
+}
+
+// connect connects to the Mongo database at MONGO_TEST_URI. The test is
+// skipped if the variable is not set. The client is disconnected once the test
+// is finished.
+func connect(t *testing.T) *mongo.Client {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("mongo connect: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		t.Fatalf("ping mongo: %v", err)
+	}
+	return client
+}
+
+// newTestContainer returns a container backed by a database of its own.
+func newTestContainer(t *testing.T, client *mongo.Client) testContainer {
+	t.Helper()
+	m := &MongoDBContainer{
+		client:   client,
+		database: client.Database(fmt.Sprintf("bookings_test_%d", time.Now().UnixNano())),
+	}
+	if err := m.createIndexes(context.Background()); err != nil {
+		t.Fatalf("create indexes: %v", err)
+	}
+	return testContainer{m}
+}
+
+// TestConformance runs the conformance test suite against the Mongo database
+// at MONGO_TEST_URI. The test is skipped if the variable is not set. The
+// database must be a replica set, since the container uses transactions.
+func TestConformance(t *testing.T) {
+	client := connect(t)
+	containertest.Run(t, func(t *testing.T) BookingsContainer {
+		return newTestContainer(t, client)
+	})
+}
+
+// TestUpsertWithoutStoredVersion checks that entries which were stored without
+// a version, e.g. by older releases, are updated by versioned upserts.
+func TestUpsertWithoutStoredVersion(t *testing.T) {
+	ctx := context.Background()
+	c := newTestContainer(t, connect(t))
+	t.Cleanup(func() { _ = c.Close() })
+
+	legacy := map[string]bson.M{
+		EventsCollection:    {"id": "e1", "name": "legacy", "booked": 1, "cancelled": false},
+		LocationsCollection: {"id": "l1", "name": "legacy"},
+	}
+	for collection, doc := range legacy {
+		if _, err := c.database.Collection(collection).InsertOne(ctx, doc); err != nil {
+			t.Fatalf("insert into %q: %v", collection, err)
+		}
+	}
+
+	if err := c.UpsertEvent(ctx, Event{ID: "e1", Name: "v1", Version: 1}); err != nil {
+		t.Fatalf("update event: %v", err)
+	}
+	if err := c.UpsertLocation(ctx, Location{ID: "l1", Name: "v1", Version: 1}); err != nil {
+		t.Fatalf("update location: %v", err)
+	}
+
+	elem, err := c.GetByID(ctx, EventsCollection, "e1")
+	if err != nil {
+		t.Fatalf("get event: %v", err)
+	}
+	if e, ok := elem.(Event); !ok || e.Name != "v1" || e.Version != 1 || e.Booked != 1 {
+		t.Errorf("want event v1 with 1 booked seat, got %+v", elem)
+	}
+	elem, err = c.GetByID(ctx, LocationsCollection, "l1")
+	if err != nil {
+		t.Fatalf("get location: %v", err)
+	}
+	if l, ok := elem.(Location); !ok || l.Name != "v1" || l.Version != 1 {
+		t.Errorf("want location v1, got %+v", elem)
+	}
+}
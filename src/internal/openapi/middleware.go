@@ -0,0 +1,228 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eventscompass/booking-service/src/internal/problem"
+	"github.com/eventscompass/booking-service/src/internal/validation"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// Middleware validates the requests and the responses of the operations of
+// the document. Invalid requests are rejected with the violated fields, while
+// invalid responses are logged and replaced with a server error, so that a
+// handler which disagrees with the document is noticed. Requests which are not
+// described by the document are passed through, so that the router rejects
+// them. The responses are buffered, therefore the middleware is meant for
+// tests and development rather than production.
+func (s *Spec) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		op, params := s.find(r)
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := s.validateRequest(w, r, op, params); err != nil {
+			problem.Write(ctx, w, fmt.Errorf("%s: %w", op.ID, err))
+			return
+		}
+
+		rec := &recorder{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if err := s.validateResponse(op, rec); err != nil {
+			slog.Error("response disagrees with the openapi document",
+				slog.String("operation", op.ID),
+				slog.Int("status", rec.status),
+				slog.String("error", err.Error()),
+			)
+			problem.Write(ctx, w, fmt.Errorf("%w: %s: invalid response", service.ErrUnexpected, op.ID))
+			return
+		}
+
+		for key, values := range rec.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(rec.status)
+		if _, err := w.Write(rec.body.Bytes()); err != nil {
+			slog.Info("failed to write response", slog.String("error", err.Error()))
+		}
+	})
+}
+
+// validateRequest validates the parameters and the body of the request. The
+// body is read and replaced, so that the handler can read it again. This
+// function returns an [*validation.Error] listing the invalid fields. This
+// function returns [service.ErrBadRequest] if the body cannot be decoded.
+func (s *Spec) validateRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	op *operation,
+	params map[string]string,
+) error {
+	var v validation.Validator
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		var (
+			value string
+			ok    bool
+		)
+		switch p.In {
+		case "path":
+			value, ok = params[p.Name]
+		case "query":
+			ok = query.Has(p.Name)
+			value = query.Get(p.Name)
+		case "header":
+			value = r.Header.Get(p.Name)
+			ok = value != ""
+		}
+		if !ok {
+			v.Check(!p.Required, p.Name, "is required")
+			continue
+		}
+		s.validate(&v, paramValue(value, p.Schema), p.Schema, p.Name)
+	}
+
+	if op.RequestBody != nil {
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, validation.MaxBodyBytes))
+		if err != nil {
+			return validation.BodyError(err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(data))
+
+		mt, ok := op.RequestBody.Content["application/json"]
+		switch {
+		case len(data) == 0 && op.RequestBody.Required:
+			return fmt.Errorf("%w: empty body", service.ErrBadRequest)
+		case len(data) > 0 && ok && mt.Schema != nil:
+			value, err := decode(data)
+			if err != nil {
+				return fmt.Errorf("%w: decode body: %v", service.ErrBadRequest, err)
+			}
+			s.validate(&v, value, mt.Schema, "")
+		}
+	}
+	return v.Err()
+}
+
+// paramValue converts the raw value of a parameter to the json value that its
+// schema expects. Values which cannot be converted are kept as strings, so
+// that they are reported as having the wrong type.
+func paramValue(raw string, sch *schema) any {
+	switch sch.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err == nil {
+			return json.Number(raw)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
+
+// validateResponse validates the status, the media type and the body of the
+// recorded response. This function returns [service.ErrUnexpected] if the
+// response is not described by the operation.
+func (s *Spec) validateResponse(op *operation, rec *recorder) error {
+	resp, ok := op.Responses[strconv.Itoa(rec.status)]
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("%w: undocumented status %d", service.ErrUnexpected, rec.status)
+	}
+
+	body := rec.body.Bytes()
+	if len(body) == 0 {
+		return nil
+	}
+	contentType := rec.header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("%w: parse content type: %v", service.ErrUnexpected, err)
+	}
+	mt, ok := resp.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%w: undocumented media type %q", service.ErrUnexpected, mediaType)
+	}
+	if mt.Schema == nil || !strings.HasSuffix(mediaType, "json") {
+		return nil
+	}
+
+	value, err := decode(body)
+	if err != nil {
+		return fmt.Errorf("%w: decode body: %v", service.ErrUnexpected, err)
+	}
+	var v validation.Validator
+	s.validate(&v, value, mt.Schema, "")
+	var verr *validation.Error
+	if errors.As(v.Err(), &verr) {
+		msgs := make([]string, len(verr.Violations))
+		for i, violation := range verr.Violations {
+			msgs[i] = fmt.Sprintf("%s %s", violation.Field, violation.Message)
+		}
+		return fmt.Errorf("%w: invalid body: %s", service.ErrUnexpected, strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+// errTrailingData is returned when a body holds more than one json value.
+var errTrailingData = errors.New("body must hold a single json value")
+
+// decode decodes a single json value, keeping the numbers as [json.Number].
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err //nolint:wrapcheck // the error is wrapped by the caller
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errTrailingData
+	}
+	return value, nil
+}
+
+// recorder is a [http.ResponseWriter] which buffers the response, so that it
+// can be validated before it is written.
+type recorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+// Header implements the [http.ResponseWriter] interface.
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+// WriteHeader implements the [http.ResponseWriter] interface.
+func (rec *recorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.status, rec.wroteHeader = status, true
+}
+
+// Write implements the [http.ResponseWriter] interface.
+func (rec *recorder) Write(data []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(data) //nolint:wrapcheck // writing to a buffer cannot fail
+}
@@ -0,0 +1,136 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+
+	"github.com/eventscompass/booking-service/src/internal/problem"
+)
+
+// event is a response body which agrees with the Event schema.
+const event = `{"id":"e1","name":"Concert","location_id":"l1",` +
+	`"start_time":"2024-01-01T12:00:00Z","end_time":"2024-01-01T14:00:00Z",` +
+	`"capacity":10,"booked":0,"version":1,"cancelled":false}`
+
+// newTestRouter returns a router behind the validation middleware, whose
+// handlers respond with the given bodies.
+func newTestRouter(t *testing.T, eventBody string) http.Handler {
+	t.Helper()
+	spec, err := Load()
+	if err != nil {
+		t.Fatalf("load document: %v", err)
+	}
+
+	mux := chi.NewMux()
+	mux.Use(spec.Middleware)
+	mux.Get("/api/events/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf8")
+		_, _ = w.Write([]byte(eventBody))
+	})
+	mux.Post("/api/bookings", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	})
+	mux.Get("/undocumented", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	return mux
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		eventBody  string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantFields []string
+	}{
+		"ValidResponse": {
+			eventBody:  event,
+			method:     http.MethodGet,
+			path:       "/api/events/e1",
+			wantStatus: http.StatusOK,
+		},
+		"InvalidResponse": {
+			eventBody:  `{"id":"e1"}`,
+			method:     http.MethodGet,
+			path:       "/api/events/e1",
+			wantStatus: http.StatusInternalServerError,
+		},
+		"UndocumentedMediaType": {
+			method:     http.MethodPost,
+			path:       "/api/bookings",
+			body:       `{"user_id":"u1","event_id":"e1"}`,
+			wantStatus: http.StatusInternalServerError,
+		},
+		"InvalidRequest": {
+			method:     http.MethodPost,
+			path:       "/api/bookings",
+			body:       `{"user_id":"","event_id":1,"seat":2}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []string{"event_id", "seat", "user_id"},
+		},
+		"InvalidParameter": {
+			method:     http.MethodGet,
+			path:       "/api/bookings?limit=many",
+			wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []string{"limit"},
+		},
+		"EmptyBody": {
+			method:     http.MethodPost,
+			path:       "/api/bookings",
+			wantStatus: http.StatusBadRequest,
+		},
+		"Undocumented": {
+			method:     http.MethodGet,
+			path:       "/undocumented",
+			wantStatus: http.StatusTeapot,
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			w := httptest.NewRecorder()
+			newTestRouter(t, test.eventBody).ServeHTTP(w, r)
+
+			if w.Code != test.wantStatus {
+				t.Fatalf("want status %d, got %d: %s", test.wantStatus, w.Code, w.Body)
+			}
+			if test.wantFields == nil {
+				return
+			}
+			var d problem.Details
+			if err := json.NewDecoder(w.Body).Decode(&d); err != nil {
+				t.Fatalf("decode details: %v", err)
+			}
+			if len(d.Violations) != len(test.wantFields) {
+				t.Fatalf("want violations of %v, got %+v", test.wantFields, d.Violations)
+			}
+			for i, field := range test.wantFields {
+				if d.Violations[i].Field != field {
+					t.Errorf("want violation %d of %q, got %q", i, field, d.Violations[i].Field)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckRoutes(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatalf("load document: %v", err)
+	}
+	mux := chi.NewMux()
+	mux.Get("/api/unknown", func(http.ResponseWriter, *http.Request) {})
+	if err := spec.CheckRoutes(mux); err == nil {
+		t.Fatal("want an error for routes which disagree with the document")
+	}
+}
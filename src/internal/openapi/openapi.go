@@ -0,0 +1,143 @@
+package openapi
+
+import (
+	_ "embed" // embeds the OpenAPI document
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+// document is the OpenAPI 3 document describing the rest api of the service.
+//
+//go:embed openapi.json
+var document []byte
+
+// refPrefix is the prefix of the references to the schemas of the document.
+const refPrefix = "#/components/schemas/"
+
+// Spec is the parsed OpenAPI document of the service. It serves the document
+// over http, checks that the routes of the router agree with it, and validates
+// the requests and responses against it. Only the subset of OpenAPI used by
+// the document is supported.
+type Spec struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+
+	// routes are the paths of the document, compiled for matching.
+	routes []route
+}
+
+// operation is a single method of a path.
+type operation struct {
+	ID          string               `json:"operationId"`
+	Parameters  []parameter          `json:"parameters"`
+	RequestBody *requestBody         `json:"requestBody"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+// parameter is a path, query or header parameter of an operation.
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+// requestBody is the body of the request of an operation.
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+// response is a response of an operation.
+type response struct {
+	Content map[string]mediaType `json:"content"`
+}
+
+// mediaType describes a body of the given media type.
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+// Load parses the OpenAPI document of the service. This function returns
+// [service.ErrUnexpected] if the document is malformed or references a schema
+// that it does not define.
+func Load() (*Spec, error) {
+	var s Spec
+	if err := json.Unmarshal(document, &s); err != nil {
+		return nil, fmt.Errorf("%w: parse openapi document: %v", service.ErrUnexpected, err)
+	}
+
+	// Resolve every reference up front, so that the validation cannot
+	// come across a dangling one.
+	var missing []string
+	s.walk(func(sch *schema) {
+		if sch.Ref == "" {
+			return
+		}
+		if _, ok := s.Components.Schemas[strings.TrimPrefix(sch.Ref, refPrefix)]; !ok {
+			missing = append(missing, sch.Ref)
+		}
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: openapi document references missing schemas: %s",
+			service.ErrUnexpected, strings.Join(missing, ", "))
+	}
+
+	s.routes = compile(s.Paths)
+	return &s, nil
+}
+
+// walk calls f for every schema of the document, including the nested ones.
+func (s *Spec) walk(f func(*schema)) {
+	var visit func(*schema)
+	visit = func(sch *schema) {
+		if sch == nil {
+			return
+		}
+		f(sch)
+		for _, p := range sch.Properties {
+			visit(p)
+		}
+		visit(sch.Items)
+		if sch.AdditionalProperties != nil {
+			visit(sch.AdditionalProperties.Schema)
+		}
+	}
+
+	for _, sch := range s.Components.Schemas {
+		visit(sch)
+	}
+	for _, item := range s.Paths {
+		for _, op := range item {
+			for _, p := range op.Parameters {
+				visit(p.Schema)
+			}
+			if op.RequestBody != nil {
+				for _, mt := range op.RequestBody.Content {
+					visit(mt.Schema)
+				}
+			}
+			for _, resp := range op.Responses {
+				for _, mt := range resp.Content {
+					visit(mt.Schema)
+				}
+			}
+		}
+	}
+}
+
+// ServeHTTP implements the [http.Handler] interface. It responds with the
+// OpenAPI document.
+func (s *Spec) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf8")
+	if _, err := w.Write(document); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,132 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+// route is a path of the document, split into segments. Path parameters are
+// written as "{name}", both in OpenAPI and in chi.
+type route struct {
+	path     string
+	segments []string
+	ops      map[string]*operation
+}
+
+// compile compiles the paths of the document. The routes are ordered so that
+// routes with more literal segments are matched first, as the router does.
+func compile(paths map[string]map[string]*operation) []route {
+	routes := make([]route, 0, len(paths))
+	for path, ops := range paths {
+		routes = append(routes, route{
+			path:     path,
+			segments: strings.Split(strings.Trim(path, "/"), "/"),
+			ops:      ops,
+		})
+	}
+	slices.SortFunc(routes, func(a, b route) int {
+		if n := b.literals() - a.literals(); n != 0 {
+			return n
+		}
+		return strings.Compare(a.path, b.path)
+	})
+	return routes
+}
+
+// literals returns the number of segments that are not parameters.
+func (r *route) literals() int {
+	n := 0
+	for _, seg := range r.segments {
+		if !isParam(seg) {
+			n++
+		}
+	}
+	return n
+}
+
+// match matches the url path against the route and returns the values of the
+// path parameters. It returns false if the path does not match.
+func (r *route) match(path string) (map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, seg := range r.segments {
+		if isParam(seg) {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(seg, "{}")] = segments[i]
+			continue
+		}
+		if seg != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func isParam(seg string) bool {
+	return strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")
+}
+
+// find returns the operation of the document which serves the request,
+// together with the values of its path parameters. It returns nil if the
+// document does not describe the request.
+func (s *Spec) find(r *http.Request) (*operation, map[string]string) {
+	for i := range s.routes {
+		params, ok := s.routes[i].match(r.URL.Path)
+		if !ok {
+			continue
+		}
+		return s.routes[i].ops[strings.ToLower(r.Method)], params
+	}
+	return nil, nil
+}
+
+// CheckRoutes checks that the routes of the router and the operations of the
+// document agree: every route must be documented and every operation must be
+// routed. This function returns [service.ErrUnexpected] listing every
+// disagreement.
+func (s *Spec) CheckRoutes(routes chi.Routes) error {
+	routed := make(map[string]bool)
+	walk := func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routed[method+" "+route] = true
+		return nil
+	}
+	if err := chi.Walk(routes, walk); err != nil {
+		return fmt.Errorf("%w: walk routes: %v", service.ErrUnexpected, err)
+	}
+
+	documented := make(map[string]bool)
+	for path, ops := range s.Paths {
+		for method := range ops {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	var problems []string
+	for r := range routed {
+		if !documented[r] {
+			problems = append(problems, fmt.Sprintf("route %s is not documented", r))
+		}
+	}
+	for op := range documented {
+		if !routed[op] {
+			problems = append(problems, fmt.Sprintf("operation %s is not routed", op))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	slices.Sort(problems)
+	return fmt.Errorf("%w: routes disagree with the openapi document: %s",
+		service.ErrUnexpected, strings.Join(problems, "; "))
+}
@@ -0,0 +1,175 @@
+package openapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eventscompass/booking-service/src/internal/validation"
+)
+
+// schema is the subset of the OpenAPI schema object used by the document.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *additional        `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+}
+
+// additional tells whether an object may have properties that its schema does
+// not define, and the schema of those properties.
+type additional struct {
+	Allowed bool
+	Schema  *schema
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. The value is
+// either a boolean or a schema.
+func (a *additional) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed, a.Schema = true, new(schema)
+	return json.Unmarshal(data, a.Schema) //nolint:wrapcheck // the error is wrapped by the caller
+}
+
+// validate validates the json value, decoded with [json.Decoder.UseNumber],
+// against the schema and records every violation. Fields are named by their
+// path from the root of the value, which is named "body".
+func (s *Spec) validate(v *validation.Validator, value any, sch *schema, field string) {
+	if sch.Ref != "" {
+		sch = s.Components.Schemas[strings.TrimPrefix(sch.Ref, refPrefix)]
+	}
+	at := field
+	if at == "" {
+		at = "body"
+	}
+	if value == nil {
+		v.Check(sch.Nullable || sch.Type == "", at, "must not be null")
+		return
+	}
+
+	switch sch.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if v.Check(ok, at, "must be of type object") {
+			s.validateObject(v, obj, sch, field)
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if v.Check(ok, at, "must be of type array") && sch.Items != nil {
+			for i, item := range arr {
+				s.validate(v, item, sch.Items, fmt.Sprintf("%s[%d]", at, i))
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if v.Check(ok, at, "must be of type string") {
+			validateString(v, str, sch, at)
+		}
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if v.Check(ok, at, "must be of type "+sch.Type) {
+			validateNumber(v, num, sch, at)
+		}
+	case "boolean":
+		_, ok := value.(bool)
+		v.Check(ok, at, "must be of type boolean")
+	}
+}
+
+func (s *Spec) validateObject(
+	v *validation.Validator,
+	obj map[string]any,
+	sch *schema,
+	field string,
+) {
+	for _, name := range sch.Required {
+		_, ok := obj[name]
+		v.Check(ok, join(field, name), "is required")
+	}
+
+	// Check the properties in order, so that the violations are
+	// reported in a stable order.
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		prop, ok := sch.Properties[name]
+		switch {
+		case ok:
+			s.validate(v, obj[name], prop, join(field, name))
+		case sch.AdditionalProperties == nil:
+		case !sch.AdditionalProperties.Allowed:
+			v.Check(false, join(field, name), "is not allowed")
+		case sch.AdditionalProperties.Schema != nil:
+			s.validate(v, obj[name], sch.AdditionalProperties.Schema, join(field, name))
+		}
+	}
+}
+
+func validateString(v *validation.Validator, str string, sch *schema, field string) {
+	if len(sch.Enum) > 0 {
+		v.Check(slices.Contains(sch.Enum, any(str)), field,
+			fmt.Sprintf("must be one of %v", sch.Enum))
+	}
+	if sch.MinLength != nil {
+		v.Check(utf8.RuneCountInString(str) >= *sch.MinLength, field,
+			fmt.Sprintf("must be at least %d characters", *sch.MinLength))
+	}
+	if sch.MaxLength != nil {
+		v.Check(utf8.RuneCountInString(str) <= *sch.MaxLength, field,
+			fmt.Sprintf("must be at most %d characters", *sch.MaxLength))
+	}
+
+	switch sch.Format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, str)
+		v.Check(err == nil, field, "must be an RFC 3339 date-time")
+	case "byte":
+		_, err := base64.StdEncoding.DecodeString(str)
+		v.Check(err == nil, field, "must be base64 encoded")
+	}
+}
+
+func validateNumber(v *validation.Validator, num json.Number, sch *schema, field string) {
+	if sch.Type == "integer" {
+		_, err := num.Int64()
+		if !v.Check(err == nil, field, "must be of type integer") {
+			return
+		}
+	}
+	f, err := num.Float64()
+	if !v.Check(err == nil, field, "must be of type number") {
+		return
+	}
+	if sch.Minimum != nil {
+		v.Check(f >= *sch.Minimum, field, fmt.Sprintf("must be at least %v", *sch.Minimum))
+	}
+	if sch.Maximum != nil {
+		v.Check(f <= *sch.Maximum, field, fmt.Sprintf("must be at most %v", *sch.Maximum))
+	}
+}
+
+// join joins the path of a field with the name of one of its properties.
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
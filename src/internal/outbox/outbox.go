@@ -0,0 +1,190 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// NewMessage creates a new [OutboxMessage] with the encoded payload, which is
+// to be published to the given topic. The message should be stored in the
+// [OutboxCollection] in the same transaction as the change that it announces.
+// This function returns [service.ErrBadRequest] if the payload cannot be
+// encoded.
+func NewMessage(topic string, payload any) (*OutboxMessage, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: marshal payload: %v", service.ErrBadRequest, err)
+	}
+
+	id, err := NewID()
+	if err != nil {
+		return nil, fmt.Errorf("message id: %w", err)
+	}
+
+	return &OutboxMessage{
+		ID:        id,
+		Topic:     topic,
+		Payload:   body,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// Relay drains the messages from the [OutboxCollection] to the message bus.
+// Messages are delivered at-least-once: a message is marked as sent only
+// after it was successfully published, so a crash in between will cause the
+// message to be published again. Messages which cannot be published are
+// parked, i.e. set aside, once they are known to be undeliverable or once their
+// attempts are exhausted, so that they do not block the messages after them.
+// Sent and parked messages are kept for a while, and then purged from the
+// outbox.
+type Relay struct {
+	// bookingsDB is the container holding the outbox.
+	bookingsDB BookingsContainer
+
+	// bus is the message bus to which messages are published.
+	bus service.MessageBus
+
+	// cfg is used to configure the relay.
+	cfg *Config
+
+	// wake is used to trigger a poll before the interval elapses.
+	wake chan struct{}
+}
+
+// batchSize is the maximum number of messages fetched from the outbox at once.
+const batchSize = 100
+
+// Config holds configuration variables for the [Relay].
+type Config struct {
+	// Interval is the time to wait between two polls of the outbox.
+	Interval time.Duration
+
+	// Retention is how long sent and parked messages are kept in
+	// the outbox, before they are purged.
+	Retention time.Duration
+
+	// MaxAttempts is the maximum number of attempts to publish a
+	// message, before it is parked.
+	MaxAttempts int
+
+	// PublishTimeout is the maximum time that publishing a single
+	// message is allowed to take.
+	PublishTimeout time.Duration
+}
+
+// NewRelay creates a new [Relay] instance. The outbox will be polled every
+// configured interval, once [Relay.Run] is called.
+func NewRelay(bookingsDB BookingsContainer, bus service.MessageBus, cfg *Config) *Relay {
+	return &Relay{
+		bookingsDB: bookingsDB,
+		bus:        bus,
+		cfg:        cfg,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Notify signals the relay that new messages were added to the outbox. This
+// function does not block.
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default: // a poll is already scheduled
+	}
+}
+
+// Run drains the outbox until the context is cancelled. Sent messages are
+// purged on every poll, but not when the relay is notified of new messages.
+// This is a blocking function.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		purge := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purge = true
+		case <-r.wake:
+		}
+		if err := r.drain(ctx); err != nil {
+			slog.Warn("failed to drain outbox", slog.String("error", err.Error()))
+		}
+		if purge {
+			r.purge(ctx)
+		}
+	}
+}
+
+// purge removes the messages that were sent or parked longer than the
+// retention ago.
+func (r *Relay) purge(ctx context.Context) {
+	n, err := r.bookingsDB.PurgeMessages(ctx, time.Now().Add(-r.cfg.Retention))
+	if err != nil {
+		slog.Warn("failed to purge outbox", slog.String("error", err.Error()))
+		return
+	}
+	if n > 0 {
+		slog.Debug("purged messages from outbox", slog.Int("count", n))
+	}
+}
+
+// drain publishes the pending messages in the order in which they were
+// created. It stops at the first message that fails to be published, so that
+// the ordering of the messages is preserved, unless the message is parked.
+func (r *Relay) drain(ctx context.Context) error {
+	for {
+		msgs, err := r.bookingsDB.PendingMessages(ctx, batchSize)
+		if err != nil {
+			return fmt.Errorf("pending messages: %w", err)
+		}
+
+		for _, msg := range msgs {
+			sendErr := r.publish(ctx, &msg)
+			if err := r.bookingsDB.RecordAttempt(ctx, msg.ID, sendErr); err != nil {
+				return fmt.Errorf("record attempt: %w", err)
+			}
+			if sendErr == nil {
+				continue
+			}
+
+			// The attempt is not held against the message if the relay
+			// is stopping.
+			undeliverable := errors.Is(sendErr, ErrUndeliverable)
+			if ctx.Err() != nil || !undeliverable && msg.Attempts+1 < r.cfg.MaxAttempts {
+				return fmt.Errorf("publish %q: %w", msg.ID, sendErr)
+			}
+			if err := r.bookingsDB.ParkMessage(ctx, msg.ID); err != nil {
+				return fmt.Errorf("park message: %w", err)
+			}
+			slog.Warn(
+				"parked outbox message",
+				slog.String("id", msg.ID),
+				slog.String("topic", msg.Topic),
+				slog.Int("attempts", msg.Attempts+1),
+				slog.String("error", sendErr.Error()),
+			)
+		}
+
+		if len(msgs) < batchSize {
+			return nil
+		}
+	}
+}
+
+// publish publishes the message to the bus. The publish is bounded by the
+// configured timeout, so that a bus which does not respond cannot stall the
+// relay.
+func (r *Relay) publish(ctx context.Context, msg *OutboxMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.PublishTimeout)
+	defer cancel()
+	return r.bus.Publish(ctx, msg.Topic, msg.Payload) //nolint:wrapcheck // wrapped by the caller
+}
@@ -0,0 +1,145 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/memory"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// poisonBus is a message bus which fails to publish the messages of the
+// "poison" topic with the given error, or blocks until the context is done if
+// the error is nil.
+type poisonBus struct {
+	*memory.Bus
+	err error
+}
+
+func (b *poisonBus) Publish(ctx context.Context, topic string, msg []byte) error {
+	if topic != "poison" {
+		return b.Bus.Publish(ctx, topic, msg)
+	}
+	if b.err != nil {
+		return b.err
+	}
+	<-ctx.Done()
+	return fmt.Errorf("%w: %v", service.ErrTimeOut, ctx.Err())
+}
+
+func TestRelayParksPoisonMessage(t *testing.T) {
+	tests := map[string]struct {
+		err        error
+		wantDrains int
+	}{
+		"Undeliverable": {
+			err:        fmt.Errorf("%w: no queue is bound", ErrUndeliverable),
+			wantDrains: 1,
+		},
+		"Failing": {
+			err:        fmt.Errorf("%w: broker down", service.ErrConnectionClosed),
+			wantDrains: 3,
+		},
+		"Hanging": {
+			wantDrains: 3,
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := memory.NewMemoryContainer()
+			t.Cleanup(func() { _ = db.Close() })
+			bus := &poisonBus{Bus: memory.NewBus(), err: test.err}
+			t.Cleanup(func() { _ = bus.Close() })
+			relay := NewRelay(db, bus, &Config{
+				Interval:       time.Minute,
+				Retention:      time.Hour,
+				MaxAttempts:    3,
+				PublishTimeout: 10 * time.Millisecond,
+			})
+
+			// The poison message is followed by a message which can be
+			// published.
+			start := time.Now().UTC()
+			msgs := []OutboxMessage{
+				{ID: "m1", Topic: "poison", Payload: []byte("{}"), CreatedAt: start},
+				{ID: "m2", Topic: "event.booked", Payload: []byte("{}"), CreatedAt: start.Add(1)},
+			}
+			for _, msg := range msgs {
+				if err := db.Create(ctx, OutboxCollection, msg); err != nil {
+					t.Fatalf("create message: %v", err)
+				}
+			}
+
+			// The poison message holds back the other message until it
+			// is parked.
+			for i := 1; i < test.wantDrains; i++ {
+				if err := relay.drain(ctx); err == nil {
+					t.Fatalf("drain %d: want an error", i)
+				}
+				if n := len(bus.Published()); n != 0 {
+					t.Fatalf("drain %d: want no published messages, got %d", i, n)
+				}
+			}
+			if err := relay.drain(ctx); err != nil {
+				t.Fatalf("drain %d: %v", test.wantDrains, err)
+			}
+			published := bus.Published()
+			if len(published) != 1 || published[0].Topic != "event.booked" {
+				t.Fatalf("want the other message to be published, got %+v", published)
+			}
+
+			elem, err := db.GetByID(ctx, OutboxCollection, "m1")
+			if err != nil {
+				t.Fatalf("get message: %v", err)
+			}
+			parked, ok := elem.(OutboxMessage)
+			if !ok {
+				t.Fatalf("want type OutboxMessage, got %T", elem)
+			}
+			if !parked.Parked || parked.Attempts != test.wantDrains || parked.LastError == "" {
+				t.Errorf("want the message parked after %d attempts, got %+v",
+					test.wantDrains, parked)
+			}
+			pending, err := db.PendingMessages(ctx, batchSize)
+			if err != nil || len(pending) != 0 {
+				t.Errorf("want no pending messages, got %+v and %v", pending, err)
+			}
+		})
+	}
+}
+
+func TestRelayStopping(t *testing.T) {
+	db := memory.NewMemoryContainer()
+	t.Cleanup(func() { _ = db.Close() })
+	bus := &poisonBus{Bus: memory.NewBus()}
+	t.Cleanup(func() { _ = bus.Close() })
+	relay := NewRelay(db, bus, &Config{
+		Interval:       time.Minute,
+		Retention:      time.Hour,
+		MaxAttempts:    1,
+		PublishTimeout: time.Minute,
+	})
+
+	msg := OutboxMessage{ID: "m1", Topic: "poison", Payload: []byte("{}")}
+	if err := db.Create(context.Background(), OutboxCollection, msg); err != nil {
+		t.Fatalf("create message: %v", err)
+	}
+
+	// A message is not parked because the relay was stopped while the
+	// message was being published.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := relay.drain(ctx); !errors.Is(err, service.ErrTimeOut) {
+		t.Fatalf("want error %v, got %v", service.ErrTimeOut, err)
+	}
+	pending, err := db.PendingMessages(context.Background(), batchSize)
+	if err != nil || len(pending) != 1 {
+		t.Errorf("want the message to be pending, got %+v and %v", pending, err)
+	}
+}
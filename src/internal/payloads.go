@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"time"
+)
+
+var (
+	// Topics.
+
+	// BookingCancelledTopic is the routing key with which messages
+	// about cancelled bookings will be published.
+	BookingCancelledTopic = "booking.cancelled"
+
+	// HoldExpiredTopic is the routing key with which messages
+	// about expired seat holds will be published.
+	HoldExpiredTopic = "hold.expired"
+
+	// WaitlistPromotedTopic is the routing key with which messages
+	// about users promoted from a waitlist will be published.
+	WaitlistPromotedTopic = "waitlist.promoted"
+
+	// EventUpdatedTopic is the routing key with which messages
+	// about updated events are published by the events service.
+	EventUpdatedTopic = "event.updated"
+
+	// EventCancelledTopic is the routing key with which messages
+	// about cancelled events are published by the events service.
+	EventCancelledTopic = "event.cancelled"
+
+	// UserCreatedTopic is the routing key with which messages
+	// about created users are published by the users service.
+	UserCreatedTopic = "user.created"
+)
+
+// BookingCancelled is the payload for notifying for the cancellation of a
+// booking.
+type BookingCancelled struct {
+	BookingID string `json:"booking_id"`
+	EventID   string `json:"event_id"`
+	UserID    string `json:"user_id"`
+
+	// Reason is set if the booking was not cancelled by its user,
+	// e.g. because the event was cancelled.
+	Reason string `json:"reason,omitempty"`
+}
+
+// HoldExpired is the payload for notifying for the expiration of a seat hold.
+type HoldExpired struct {
+	HoldID  string `json:"hold_id"`
+	EventID string `json:"event_id"`
+	UserID  string `json:"user_id"`
+}
+
+// WaitlistPromoted is the payload for notifying that a user was promoted from
+// the waitlist of an event. A seat is held for the user until the hold expires.
+type WaitlistPromoted struct {
+	HoldID    string    `json:"hold_id"`
+	EventID   string    `json:"event_id"`
+	UserID    string    `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EventUpdated is the payload for notifying for the update of an event. The
+// capacity is optional, and the version orders the updates of the event. An
+// update without a version is always applied.
+type EventUpdated struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	LocationID string    `json:"location_id"`
+	Start      time.Time `json:"start_time"`
+	End        time.Time `json:"end_time"`
+	Capacity   int       `json:"capacity"`
+	Version    int64     `json:"version"`
+}
+
+// EventCancelled is the payload for notifying for the cancellation of an event.
+type EventCancelled struct {
+	ID string `json:"id"`
+}
+
+// UserCreated is the payload for notifying for the creation of a user.
+type UserCreated struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
@@ -0,0 +1,192 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/eventscompass/booking-service/src/internal/auth"
+	"github.com/eventscompass/booking-service/src/internal/requestid"
+	"github.com/eventscompass/booking-service/src/internal/validation"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// ContentType is the media type of a problem details response.
+const ContentType = "application/problem+json"
+
+// Details is the body of an error response, as defined by RFC 7807. Besides the
+// standard members, it carries a machine-readable code of the error and the id
+// of the failed request.
+type Details struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// Violations lists the invalid fields of the request, if any.
+	Violations []validation.Violation `json:"violations,omitempty"`
+}
+
+// errMethodNotAllowed is returned when the route does not support the method of
+// the request. The framework does not define such an error, since it is only
+// returned by the router.
+var errMethodNotAllowed = errors.New("method not allowed")
+
+// class is a class of errors which map to the same response.
+type class struct {
+	err error
+
+	// status is the http status code of the errors. It is zero for the
+	// errors defined by the framework, whose status code is the one set by
+	// [service.HTTPError].
+	status int
+
+	code string
+}
+
+// classes maps every class of errors to its error code, and the errors which
+// are not defined by the framework to their http status code. The classes are
+// checked in order, so that the context errors take precedence over the errors
+// defined by the framework, which in turn are preceded by the more specific
+// errors of the service. Errors which do not belong to any class are
+// unexpected.
+var classes = []class{
+	{context.Canceled, 0, "client_closed"},
+	{context.DeadlineExceeded, 0, "timeout"},
+	{validation.ErrInvalid, http.StatusUnprocessableEntity, "invalid_fields"},
+	{validation.ErrTooLarge, http.StatusRequestEntityTooLarge, "too_large"},
+	{auth.ErrUnauthenticated, http.StatusUnauthorized, "unauthenticated"},
+	{auth.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{errMethodNotAllowed, http.StatusMethodNotAllowed, "method_not_allowed"},
+	{service.ErrBadRequest, 0, "bad_request"},
+	{service.ErrNotAllowed, 0, "not_allowed"},
+	{service.ErrNotFound, 0, "not_found"},
+	{service.ErrAlreadyExists, 0, "already_exists"},
+	{service.ErrSpaceFull, 0, "space_full"},
+	{service.ErrTimeOut, 0, "timeout"},
+	{service.ErrConnectionClosed, 0, "unavailable"},
+	{service.ErrUnexpected, 0, "unexpected"},
+}
+
+// unexpected is the class of errors which do not belong to any other class.
+var unexpected = classes[len(classes)-1]
+
+// Write maps the provided error to the correct http status code and writes
+// the problem details of the error to the response writer `w`. The status code
+// of the errors defined by the framework is the one of [service.HTTPError],
+// which also logs them. The details of server errors are not disclosed to the
+// client, since they might contain messages of the underlying drivers. It does
+// not end the request; the caller should ensure no further writes are done to
+// w.
+func Write(ctx context.Context, w http.ResponseWriter, err error) {
+	c := classify(err)
+	d := Details{
+		Type:      "about:blank",
+		Status:    c.status,
+		Code:      c.code,
+		RequestID: requestid.FromContext(ctx),
+	}
+	attrs := []any{
+		slog.String("code", d.Code),
+		slog.String("request_id", d.RequestID),
+		slog.String("error", err.Error()),
+	}
+	if d.Status == 0 {
+		d.Status = frameworkStatus(ctx, err)
+		slog.Debug("request failed", attrs...)
+	} else {
+		slog.Info("request failed", attrs...)
+	}
+
+	d.Title = title(d.Status)
+	if d.Status < http.StatusInternalServerError {
+		d.Detail = detail(err, c)
+	}
+	var invalid *validation.Error
+	if errors.As(err, &invalid) {
+		d.Violations = invalid.Violations
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(d.Status)
+	if err := json.NewEncoder(w).Encode(&d); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+// frameworkStatus returns the http status code that [service.HTTPError] sets
+// for the error. The plain text response of the framework is discarded.
+func frameworkStatus(ctx context.Context, err error) int {
+	rec := &statusRecorder{header: make(http.Header), status: http.StatusOK}
+	service.HTTPError(ctx, rec, err)
+	return rec.status
+}
+
+// statusRecorder is a [http.ResponseWriter] which only records the status code.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+// Header implements the [http.ResponseWriter] interface.
+func (rec *statusRecorder) Header() http.Header {
+	return rec.header
+}
+
+// WriteHeader implements the [http.ResponseWriter] interface.
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+}
+
+// Write implements the [http.ResponseWriter] interface.
+func (rec *statusRecorder) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+// classify returns the class of the error.
+func classify(err error) class {
+	for _, c := range classes {
+		if errors.Is(err, c.err) {
+			return c
+		}
+	}
+	return unexpected
+}
+
+// detail returns the message of the error starting from its classification,
+// e.g. "not found: booking "42"". The messages of the callers in front of the
+// classification describe the call stack and are of no use to the client.
+func detail(err error, c class) string {
+	msg := err.Error()
+	if i := strings.Index(msg, c.err.Error()); i >= 0 {
+		return msg[i:]
+	}
+	return msg
+}
+
+// title returns a short summary of the problem with the given status code.
+func title(status int) string {
+	if status == service.StatusClientClosedConnection {
+		return "Client Closed Request"
+	}
+	return http.StatusText(status)
+}
+
+// NotFound is an [http.HandlerFunc] which responds to requests for unknown
+// routes.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Write(r.Context(), w, fmt.Errorf("%w: route %q", service.ErrNotFound, r.URL.Path))
+}
+
+// MethodNotAllowed is an [http.HandlerFunc] which responds to requests with a
+// method that the route does not support.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Write(r.Context(), w, fmt.Errorf("%w: %s %q", errMethodNotAllowed, r.Method, r.URL.Path))
+}
@@ -0,0 +1,186 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eventscompass/booking-service/src/internal/auth"
+	"github.com/eventscompass/booking-service/src/internal/requestid"
+	"github.com/eventscompass/booking-service/src/internal/validation"
+	"github.com/eventscompass/service-framework/service"
+)
+
+func TestWrite(t *testing.T) {
+	invalid := &validation.Error{Violations: []validation.Violation{
+		{Field: "user_id", Message: "is required"},
+		{Field: "event_id", Message: "is required"},
+	}}
+
+	tests := map[string]struct {
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail bool
+	}{
+		"Canceled": {
+			err:        context.Canceled,
+			wantStatus: service.StatusClientClosedConnection,
+			wantCode:   "client_closed",
+			wantDetail: true,
+		},
+		"DeadlineExceeded": {
+			err:        context.DeadlineExceeded,
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "timeout",
+		},
+		"Invalid": {
+			err:        fmt.Errorf("create booking: %w", invalid),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "invalid_fields",
+			wantDetail: true,
+		},
+		"TooLarge": {
+			err:        validation.ErrTooLarge,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   "too_large",
+			wantDetail: true,
+		},
+		"Unauthenticated": {
+			err:        fmt.Errorf("%w: missing bearer token", auth.ErrUnauthenticated),
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "unauthenticated",
+			wantDetail: true,
+		},
+		"Forbidden": {
+			err:        fmt.Errorf("%w: caller is not an admin", auth.ErrForbidden),
+			wantStatus: http.StatusForbidden,
+			wantCode:   "forbidden",
+			wantDetail: true,
+		},
+		"MethodNotAllowed": {
+			err:        errMethodNotAllowed,
+			wantStatus: http.StatusMethodNotAllowed,
+			wantCode:   "method_not_allowed",
+			wantDetail: true,
+		},
+		"BadRequest": {
+			err:        fmt.Errorf("%w: empty body", service.ErrBadRequest),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "bad_request",
+			wantDetail: true,
+		},
+		"NotAllowed": {
+			err:        fmt.Errorf("%w: event has started", service.ErrNotAllowed),
+			wantStatus: http.StatusForbidden,
+			wantCode:   "not_allowed",
+			wantDetail: true,
+		},
+		"NotFound": {
+			err:        fmt.Errorf("get booking: %w: %q", service.ErrNotFound, "42"),
+			wantStatus: http.StatusNotFound,
+			wantCode:   "not_found",
+			wantDetail: true,
+		},
+		"AlreadyExists": {
+			err:        service.ErrAlreadyExists,
+			wantStatus: http.StatusConflict,
+			wantCode:   "already_exists",
+			wantDetail: true,
+		},
+		"SpaceFull": {
+			err:        service.ErrSpaceFull,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "space_full",
+			wantDetail: true,
+		},
+		"TimeOut": {
+			err:        service.ErrTimeOut,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "timeout",
+		},
+		"ConnectionClosed": {
+			err:        service.ErrConnectionClosed,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "unavailable",
+		},
+		"Unexpected": {
+			err:        service.ErrUnexpected,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "unexpected",
+		},
+		"Unclassified": {
+			err:        errors.New("mongo: connection reset"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "unexpected",
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(requestid.Header, "req-1")
+			w := httptest.NewRecorder()
+			requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Write(r.Context(), w, test.err)
+			})).ServeHTTP(w, r)
+
+			if w.Code != test.wantStatus {
+				t.Errorf("want status %d, got %d", test.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != ContentType {
+				t.Errorf("want content type %q, got %q", ContentType, got)
+			}
+			var d Details
+			if err := json.NewDecoder(w.Body).Decode(&d); err != nil {
+				t.Fatalf("decode details: %v", err)
+			}
+			if d.Status != test.wantStatus || d.Code != test.wantCode {
+				t.Errorf("want status %d and code %q, got %d and %q",
+					test.wantStatus, test.wantCode, d.Status, d.Code)
+			}
+			if d.Title == "" || d.RequestID != "req-1" {
+				t.Errorf("want a title and the request id, got %+v", d)
+			}
+			if test.wantDetail != (d.Detail != "") {
+				t.Errorf("want detail %t, got %q", test.wantDetail, d.Detail)
+			}
+		})
+	}
+}
+
+func TestWriteDetail(t *testing.T) {
+	err := fmt.Errorf("read booking: get: %w: %q", service.ErrNotFound, "42")
+	w := httptest.NewRecorder()
+	Write(context.Background(), w, err)
+
+	var d Details
+	if err := json.NewDecoder(w.Body).Decode(&d); err != nil {
+		t.Fatalf("decode details: %v", err)
+	}
+	if !strings.HasPrefix(d.Detail, service.ErrNotFound.Error()) {
+		t.Errorf("want detail starting from the classification, got %q", d.Detail)
+	}
+}
+
+func TestWriteViolations(t *testing.T) {
+	var v validation.Validator
+	v.Check(false, "user_id", "is required")
+	v.Check(false, "date", "must be an RFC 3339 date-time")
+	w := httptest.NewRecorder()
+	Write(context.Background(), w, v.Err())
+
+	var d Details
+	if err := json.NewDecoder(w.Body).Decode(&d); err != nil {
+		t.Fatalf("decode details: %v", err)
+	}
+	if len(d.Violations) != 2 || d.Violations[0].Field != "user_id" ||
+		d.Violations[1].Field != "date" {
+		t.Errorf("want every violation, got %+v", d.Violations)
+	}
+}
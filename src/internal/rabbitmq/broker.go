@@ -0,0 +1,50 @@
+package rabbitmq
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// connection is the part of an AMQP connection which is used by the [Bus]. It
+// allows to test the reconnecting of the bus without a broker.
+type connection interface {
+	Channel() (channel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	IsClosed() bool
+	Close() error
+}
+
+// channel is the part of an AMQP channel which is used by the [Bus].
+type channel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool,
+		args amqp.Table) error
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool,
+		args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	ConsumeWithContext(ctx context.Context, queue, consumer string,
+		autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Confirm(noWait bool) error
+	NotifyReturn(c chan amqp.Return) chan amqp.Return
+	PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string,
+		mandatory, immediate bool, msg amqp.Publishing) (*amqp.DeferredConfirmation, error)
+	Close() error
+}
+
+// dialer connects to the broker.
+type dialer func() (connection, error)
+
+// amqpConnection adapts an [amqp.Connection] to the [connection] interface.
+type amqpConnection struct {
+	*amqp.Connection
+}
+
+// Channel opens a new channel on the connection.
+func (c amqpConnection) Channel() (channel, error) {
+	ch, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers
+	}
+	return ch, nil
+}
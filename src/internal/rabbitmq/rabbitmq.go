@@ -0,0 +1,493 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/service-framework/service"
+)
+
+var (
+	// ErrConnFailed is returned when we cannot establish the
+	// connection.
+	ErrConnFailed = errors.New("connection failed")
+
+	// ErrConnBroken is returned when the connection that we are
+	// trying to use is broken.
+	ErrConnBroken = errors.New("connection broken")
+
+	// ErrChanBroken is returned when the server channel that we
+	// are trying to use is broken.
+	ErrChanBroken = errors.New("connection channel broken")
+
+	// ErrUnroutable is returned when a published message is not
+	// routed to any queue, and is returned by the broker. Since
+	// publishing the message again does not change that, the error
+	// is classified as [internal.ErrUndeliverable].
+	ErrUnroutable = fmt.Errorf("%w: message unroutable", internal.ErrUndeliverable)
+)
+
+// Config holds configuration variables for connecting to a RabbitMQ broker.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+
+	// QueuePrefix is prepended to the topic in order to form the
+	// name of the queue of a subscription, e.g. the subscription
+	// for "event.created" with the prefix "booking-service"
+	// consumes from the queue "booking-service.event.created".
+	QueuePrefix string
+
+	// Prefetch is the maximum number of messages that the broker
+	// delivers to a subscription before they are acknowledged.
+	Prefetch int
+
+	// ReconnectBackoff is the time to wait before reconnecting to
+	// the broker for the first time after the connection is lost.
+	// The time doubles with every failed attempt, up to
+	// MaxReconnectBackoff. A random jitter is added to the time.
+	ReconnectBackoff    time.Duration
+	MaxReconnectBackoff time.Duration
+}
+
+// Bus is a message bus backed by a RabbitMQ message broker. Unlike the bus
+// from the service framework, every subscription consumes from a durable
+// queue with a stable name. Messages published while the service is down are
+// kept in the queue, and replicas of the service subscribed to the same topic
+// compete for the messages, instead of each receiving every message.
+//
+// If the connection to the broker is lost, then the bus reconnects in the
+// background and re-establishes the subscriptions. While the bus is
+// disconnected, publishing fails with [service.ErrConnectionClosed].
+type Bus struct {
+	// exchange is the exchange associated with this Bus.
+	exchange string
+
+	// cfg is used to configure the bus.
+	cfg *Config
+
+	// dial connects to the broker and makes sure that the exchange
+	// exists.
+	dial dialer
+
+	// mu guards conn and ready.
+	mu sync.Mutex
+
+	// conn is the connection to the RabbitMQ message broker. It is
+	// nil while the bus is reconnecting.
+	conn connection
+
+	// ready is closed once the bus is connected. A new channel is
+	// created whenever the connection is lost.
+	ready chan struct{}
+
+	// closed is closed once the bus is closed.
+	closed chan struct{}
+}
+
+var _ service.MessageBus = (*Bus)(nil)
+
+// NewBus creates a new [Bus] instance which can be used to publish to and
+// subscribe for messages on the given exchange. This function returns
+// [ErrConnFailed] in case the connection to the message broker fails. This
+// function returns [ErrConnBroken] in case the connection is broken.
+func NewBus(cfg *Config, exchange string) (*Bus, error) {
+	return newBus(cfg, exchange, func() (connection, error) {
+		return dial(cfg, exchange)
+	})
+}
+
+// newBus creates a new [Bus] instance which connects to the broker using the
+// given dialer.
+func newBus(cfg *Config, exchange string, dial dialer) (*Bus, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	b := &Bus{
+		exchange: exchange,
+		cfg:      cfg,
+		dial:     dial,
+		ready:    make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	b.connected(conn)
+	return b, nil
+}
+
+// dial connects to the broker and makes sure that the exchange exists.
+func dial(cfg *Config, exchange string) (connection, error) {
+	connInfo := fmt.Sprintf(
+		"amqp://%s:%s@%s:%d", cfg.Username, cfg.Password, cfg.Host, cfg.Port)
+	amqpConn, err := amqp.Dial(connInfo)
+	if err != nil {
+		return nil, fmt.Errorf("%w: dial broker: %v", ErrConnFailed, err)
+	}
+	conn := amqpConnection{amqpConn}
+
+	// Make sure the connection is working by declaring the exchange.
+	ch, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close() //nolint:errcheck // the connection is broken anyway
+		return nil, fmt.Errorf("%w: open channel: %v", ErrConnBroken, err)
+	}
+	defer ch.Close() //nolint:errcheck // the channel is only used once
+	if err := declareExchange(ch, exchange); err != nil {
+		_ = conn.Close() //nolint:errcheck // the connection is broken anyway
+		return nil, err
+	}
+	return conn, nil
+}
+
+// declareExchange declares the topic exchange with the given name.
+func declareExchange(ch channel, exchange string) error {
+	err := ch.ExchangeDeclare(exchange, "topic", true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("%w: declare exchange: %v", ErrChanBroken, err)
+	}
+	return nil
+}
+
+// connected installs the given connection and starts watching it. The lock
+// must not be held by the caller.
+func (b *Bus) connected(conn connection) {
+	notify := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	b.mu.Lock()
+	b.conn = conn
+	close(b.ready)
+	b.mu.Unlock()
+
+	go b.watch(notify)
+}
+
+// watch waits until the connection is closed and reconnects, unless the bus
+// was closed.
+func (b *Bus) watch(notify <-chan *amqp.Error) {
+	amqpErr := <-notify // nil if the connection was closed gracefully
+
+	b.mu.Lock()
+	b.conn = nil
+	b.ready = make(chan struct{})
+	b.mu.Unlock()
+
+	if b.isClosed() {
+		return
+	}
+	slog.Warn("lost connection to rabbitmq", slog.Any("error", amqpErr))
+	b.reconnect()
+}
+
+// reconnect connects to the broker with jittered exponential backoff, until it
+// succeeds or the bus is closed.
+func (b *Bus) reconnect() {
+	backoff := b.cfg.ReconnectBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-b.closed:
+			return
+		case <-time.After(jitter(backoff)):
+		}
+
+		conn, err := b.dial()
+		if err != nil {
+			slog.Warn(
+				"failed to reconnect to rabbitmq",
+				slog.Int("attempt", attempt),
+				slog.String("error", err.Error()),
+			)
+			backoff = min(2*backoff, b.cfg.MaxReconnectBackoff)
+			continue
+		}
+		if b.isClosed() {
+			_ = conn.Close() //nolint:errcheck // the bus is closed anyway
+			return
+		}
+		slog.Info("reconnected to rabbitmq", slog.Int("attempt", attempt))
+		b.connected(conn)
+		return
+	}
+}
+
+// jitter returns a random duration between d/2 and d, so that replicas which
+// lost the connection at the same time do not reconnect at the same time.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2))) //nolint:gosec // no secure source needed
+}
+
+// Connected returns true if the bus is currently connected to the broker.
+func (b *Bus) Connected() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.conn != nil && !b.conn.IsClosed()
+}
+
+// connection returns the current connection. If the bus is reconnecting, then
+// this function waits until the connection is re-established. This function
+// returns [service.ErrConnectionClosed] if the bus is closed.
+func (b *Bus) connection(ctx context.Context) (connection, error) {
+	for {
+		b.mu.Lock()
+		conn, ready := b.conn, b.ready
+		b.mu.Unlock()
+		if conn != nil {
+			return conn, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %v", service.ErrTimeOut, ctx.Err())
+		case <-b.closed:
+			return nil, fmt.Errorf("%w: rabbitmq bus", service.ErrConnectionClosed)
+		case <-ready:
+		}
+	}
+}
+
+// Publish publishes a message to a given topic and waits until the broker
+// confirms it. The message is persistent, i.e. it survives a restart of the
+// broker, and is mandatory, i.e. it must be routed to at least one queue. Thus
+// a nil error means that the message is stored by the broker. This function
+// returns [service.ErrConnectionClosed] in case the bus is not connected to
+// the message broker. This function returns [ErrConnBroken] in case the
+// connection is broken. This function returns [ErrChanBroken] in case
+// operations on the connection channel fail, or the broker rejects the
+// message. This function returns [ErrUnroutable] in case no queue is bound
+// for the topic.
+func (b *Bus) Publish(ctx context.Context, topic string, msg []byte) error {
+	b.mu.Lock()
+	conn := b.conn
+	b.mu.Unlock()
+	if conn == nil || conn.IsClosed() {
+		return fmt.Errorf("%w: rabbitmq connection", service.ErrConnectionClosed)
+	}
+
+	// AMQP channels are not thread-safe, thus we use a new channel for every
+	// published message, so that we can reuse the connection concurrently.
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("%w: open channel: %v", ErrConnBroken, err)
+	}
+	defer ch.Close() //nolint:errcheck // the channel is only used once
+
+	// In confirm mode the broker acknowledges every message once it has
+	// taken responsibility for it. Unroutable mandatory messages are
+	// returned before they are acknowledged, thus the return is received
+	// by the time the confirmation arrives. The channel is buffered, since
+	// the returns are delivered while holding a lock of the channel.
+	if err := ch.Confirm(false); err != nil {
+		return fmt.Errorf("%w: enable confirms: %v", ErrChanBroken, err)
+	}
+	returns := ch.NotifyReturn(make(chan amqp.Return, 1))
+
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(
+		ctx,
+		b.exchange, // exchange
+		topic,      // routing key
+		true,       // mandatory
+		false,      // immediate
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         msg,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("%w: publish message: %v", ErrChanBroken, err)
+	}
+
+	acked, err := confirm.WaitContext(ctx)
+	switch {
+	case err != nil:
+		return fmt.Errorf("%w: wait for confirmation: %v", service.ErrTimeOut, err)
+	case !acked:
+		return fmt.Errorf("%w: message was not confirmed", ErrChanBroken)
+	}
+	select {
+	case ret := <-returns:
+		return fmt.Errorf("%w: topic %q: %s", ErrUnroutable, topic, ret.ReplyText)
+	default:
+		return nil
+	}
+}
+
+// Subscribe subscribes to the given topic. The messages are consumed from a
+// durable queue, whose name is the topic prefixed with the configured queue
+// prefix. The queue is kept when the subscription is cancelled. The event
+// handler callback will be executed on every received message, and the
+// message is acknowledged after the handler returns, or requeued if the
+// handler called [internal.Requeue] on its context. If the connection to the
+// broker is lost, then the subscription is re-established once the bus has
+// reconnected. This function returns [service.ErrConnectionClosed] in case the
+// bus is closed. This is a blocking function. Canceling the context will
+// cancel the subscription.
+func (b *Bus) Subscribe(ctx context.Context, topic string, h service.EventHandler) error {
+	for {
+		conn, err := b.connection(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = b.subscribe(ctx, conn, topic, h)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn(
+			"subscription interrupted, resubscribing",
+			slog.String("topic", topic),
+			slog.String("error", err.Error()),
+		)
+
+		// Wait a bit, so that a persistent failure of the channel does not
+		// result in a busy loop.
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(jitter(b.cfg.ReconnectBackoff)):
+		}
+	}
+}
+
+// subscribe consumes the messages of the topic over the given connection,
+// until the context is cancelled or the connection fails.
+func (b *Bus) subscribe(
+	ctx context.Context,
+	conn connection,
+	topic string,
+	h service.EventHandler,
+) error {
+	// AMQP channels are not thread-safe, thus we need to use a separate channel
+	// for every subscription, so that we can reuse the connection concurrently.
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("%w: open channel: %v", ErrConnBroken, err)
+	}
+	defer ch.Close() //nolint:errcheck // unacknowledged messages are requeued
+
+	msgs, err := b.consume(ctx, ch, topic)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-msgs:
+			if !ok {
+				return fmt.Errorf("%w: deliveries closed", ErrChanBroken)
+			}
+
+			// Ack the message only after we have finished processing, unless
+			// the handler asked for it to be delivered again.
+			hctx, requeued := internal.WithDelivery(ctx)
+			h(hctx, msg.Body)
+			if requeued() {
+				err = msg.Nack(false, true)
+			} else {
+				err = msg.Ack(false)
+			}
+			if err != nil {
+				slog.Warn(
+					"failed to settle message",
+					slog.String("topic", topic),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+	}
+}
+
+// consume declares the queue of the subscription for the given topic, binds it
+// to the exchange, and starts consuming from it.
+func (b *Bus) consume(
+	ctx context.Context,
+	ch channel,
+	topic string,
+) (<-chan amqp.Delivery, error) {
+	if err := ch.Qos(b.cfg.Prefetch, 0, false); err != nil {
+		return nil, fmt.Errorf("%w: set prefetch: %v", ErrChanBroken, err)
+	}
+
+	// Before binding the queue, make sure the exchange exists.
+	if err := declareExchange(ch, b.exchange); err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%s.%s", b.cfg.QueuePrefix, topic)
+	q, err := ch.QueueDeclare(
+		name,  // name
+		true,  // durable
+		false, // auto-delete
+		false, // exclusive
+		false, // no-wait
+		nil,   // args
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: declare queue: %v", ErrChanBroken, err)
+	}
+	if err := ch.QueueBind(q.Name, topic, b.exchange, false, nil); err != nil {
+		return nil, fmt.Errorf("%w: bind queue: %v", ErrChanBroken, err)
+	}
+
+	msgs, err := ch.ConsumeWithContext(
+		ctx,
+		q.Name, // queue
+		"",     // consumer
+		false,  // auto-ack
+		false,  // exclusive
+		false,  // no-local
+		false,  // no-wait
+		nil,    // args
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: consume queue: %v", ErrChanBroken, err)
+	}
+	return msgs, nil
+}
+
+// Close closes the connection to the message broker and releases all
+// associated resources. Subscriptions return [service.ErrConnectionClosed].
+// This function returns [ErrConnBroken] if it fails to close the connection.
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.isClosed() {
+		return nil
+	}
+	close(b.closed)
+	if b.conn == nil {
+		return nil
+	}
+	if err := b.conn.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+		return fmt.Errorf("%w: close conn: %v", ErrConnBroken, err)
+	}
+	return nil
+}
+
+// isClosed returns true if the bus is closed.
+func (b *Bus) isClosed() bool {
+	select {
+	case <-b.closed:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,404 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// waitTimeout is the time to wait for the bus to reconnect or resubscribe.
+const waitTimeout = time.Second
+
+// fakeBroker hands out fake connections, and records the consumers which are
+// started on them and how their deliveries are settled.
+type fakeBroker struct {
+	// mu guards all fields below.
+	mu sync.Mutex
+
+	// failing makes dialing fail.
+	failing bool
+
+	// dials is the number of attempts to connect.
+	dials int
+
+	// conns are the established connections, in order.
+	conns []*fakeConn
+
+	// settled records how the deliveries were settled, by tag.
+	settled map[uint64]string
+
+	// consumers receives the deliveries channel of every consumer
+	// that is started.
+	consumers chan chan amqp.Delivery
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		settled:   make(map[uint64]string),
+		consumers: make(chan chan amqp.Delivery, 10),
+	}
+}
+
+func (fb *fakeBroker) dial() (connection, error) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.dials++
+	if fb.failing {
+		return nil, fmt.Errorf("%w: broker is down", ErrConnFailed)
+	}
+	conn := &fakeConn{broker: fb}
+	fb.conns = append(fb.conns, conn)
+	return conn, nil
+}
+
+func (fb *fakeBroker) setFailing(failing bool) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.failing = failing
+}
+
+// counts returns the number of attempts to connect and of connections.
+func (fb *fakeBroker) counts() (dials, conns int) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	return fb.dials, len(fb.conns)
+}
+
+// lastConn returns the most recent connection.
+func (fb *fakeBroker) lastConn() *fakeConn {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	return fb.conns[len(fb.conns)-1]
+}
+
+// consumer waits for the next consumer to be started.
+func (fb *fakeBroker) consumer(t *testing.T) chan amqp.Delivery {
+	t.Helper()
+	select {
+	case deliveries := <-fb.consumers:
+		return deliveries
+	case <-time.After(waitTimeout):
+		t.Fatal("want a consumer to be started")
+		return nil
+	}
+}
+
+func (fb *fakeBroker) settle(tag uint64, outcome string) error {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.settled[tag] = outcome
+	return nil
+}
+
+func (fb *fakeBroker) Ack(tag uint64, _ bool) error { return fb.settle(tag, "ack") }
+
+func (fb *fakeBroker) Nack(tag uint64, _, _ bool) error { return fb.settle(tag, "nack") }
+
+func (fb *fakeBroker) Reject(tag uint64, _ bool) error { return fb.settle(tag, "reject") }
+
+// fakeConn is a connection of the [fakeBroker].
+type fakeConn struct {
+	broker *fakeBroker
+
+	// mu guards all fields below.
+	mu       sync.Mutex
+	closed   bool
+	notify   []chan *amqp.Error
+	channels []*fakeChannel
+}
+
+func (c *fakeConn) Channel() (channel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, amqp.ErrClosed
+	}
+	ch := &fakeChannel{conn: c}
+	c.channels = append(c.channels, ch)
+	return ch, nil
+}
+
+func (c *fakeConn) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		close(receiver)
+	} else {
+		c.notify = append(c.notify, receiver)
+	}
+	return receiver
+}
+
+func (c *fakeConn) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *fakeConn) Close() error {
+	c.drop(nil)
+	return nil
+}
+
+// drop closes the connection and its channels. A nil error means that the
+// connection was closed gracefully.
+func (c *fakeConn) drop(amqpErr *amqp.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for _, n := range c.notify {
+		if amqpErr != nil {
+			n <- amqpErr
+		}
+		close(n)
+	}
+	for _, ch := range c.channels {
+		_ = ch.Close()
+	}
+}
+
+// fakeChannel is a channel of a [fakeConn]. Publishing is not supported.
+type fakeChannel struct {
+	channel
+
+	conn *fakeConn
+
+	// mu guards all fields below.
+	mu         sync.Mutex
+	closed     bool
+	deliveries chan amqp.Delivery
+}
+
+func (*fakeChannel) ExchangeDeclare(string, string, bool, bool, bool, bool, amqp.Table) error {
+	return nil
+}
+
+func (*fakeChannel) Qos(int, int, bool) error { return nil }
+
+func (*fakeChannel) QueueDeclare(
+	name string, _, _, _, _ bool, _ amqp.Table,
+) (amqp.Queue, error) {
+	return amqp.Queue{Name: name}, nil
+}
+
+func (*fakeChannel) QueueBind(string, string, string, bool, amqp.Table) error { return nil }
+
+func (ch *fakeChannel) ConsumeWithContext(
+	context.Context, string, string, bool, bool, bool, bool, amqp.Table,
+) (<-chan amqp.Delivery, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.closed {
+		return nil, amqp.ErrClosed
+	}
+	ch.deliveries = make(chan amqp.Delivery)
+	ch.conn.broker.consumers <- ch.deliveries
+	return ch.deliveries, nil
+}
+
+func (ch *fakeChannel) Close() error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if !ch.closed {
+		ch.closed = true
+		if ch.deliveries != nil {
+			close(ch.deliveries)
+		}
+	}
+	return nil
+}
+
+// newTestBus returns a bus connected to the fake broker, which reconnects
+// within a few milliseconds.
+func newTestBus(t *testing.T, fb *fakeBroker) *Bus {
+	t.Helper()
+	cfg := &Config{
+		QueuePrefix:         "booking-service",
+		Prefetch:            1,
+		ReconnectBackoff:    time.Millisecond,
+		MaxReconnectBackoff: 4 * time.Millisecond,
+	}
+	b, err := newBus(cfg, "events", fb.dial)
+	if err != nil {
+		t.Fatalf("new bus: %v", err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+	return b
+}
+
+// waitFor waits until the condition holds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("want %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	fb := newFakeBroker()
+	b := newTestBus(t, fb)
+	if !b.Connected() {
+		t.Fatal("want the bus to be connected")
+	}
+
+	// While the broker is down, the bus keeps trying to reconnect, and
+	// publishing fails.
+	fb.setFailing(true)
+	fb.lastConn().drop(&amqp.Error{Code: amqp.ConnectionForced, Reason: "broker restarted"})
+	waitFor(t, "the bus to be disconnected", func() bool { return !b.Connected() })
+	waitFor(t, "several attempts to reconnect", func() bool {
+		dials, _ := fb.counts()
+		return dials >= 3
+	})
+	err := b.Publish(context.Background(), "event.created", []byte(`{}`))
+	if !errors.Is(err, service.ErrConnectionClosed) {
+		t.Errorf("want error %v, got %v", service.ErrConnectionClosed, err)
+	}
+
+	fb.setFailing(false)
+	waitFor(t, "the bus to reconnect", b.Connected)
+	if _, conns := fb.counts(); conns != 2 {
+		t.Errorf("want 2 connections, got %d", conns)
+	}
+}
+
+func TestReconnectStopsWhenClosed(t *testing.T) {
+	fb := newFakeBroker()
+	b := newTestBus(t, fb)
+
+	fb.setFailing(true)
+	fb.lastConn().drop(&amqp.Error{Code: amqp.ConnectionForced, Reason: "broker restarted"})
+	waitFor(t, "an attempt to reconnect", func() bool {
+		dials, _ := fb.counts()
+		return dials >= 2
+	})
+
+	// Subscriptions waiting for the connection end once the bus is closed.
+	done := make(chan error, 1)
+	go func() {
+		done <- b.Subscribe(context.Background(), "event.created", func(context.Context, []byte) {})
+	}()
+	if err := b.Close(); err != nil {
+		t.Fatalf("close bus: %v", err)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, service.ErrConnectionClosed) {
+			t.Errorf("want error %v, got %v", service.ErrConnectionClosed, err)
+		}
+	case <-time.After(waitTimeout):
+		t.Fatal("want the subscription to end")
+	}
+
+	// Allow an attempt which was in flight to finish.
+	time.Sleep(10 * time.Millisecond)
+	dials, _ := fb.counts()
+	time.Sleep(20 * time.Millisecond)
+	if after, _ := fb.counts(); after != dials {
+		t.Errorf("want no attempts to reconnect after closing, got %d", after-dials)
+	}
+}
+
+func TestResubscribe(t *testing.T) {
+	tests := map[string]struct {
+		// drop breaks the subscription of the bus.
+		drop      func(fb *fakeBroker)
+		wantConns int
+	}{
+		"ConnectionDropped": {
+			drop: func(fb *fakeBroker) {
+				fb.lastConn().drop(&amqp.Error{Code: amqp.ConnectionForced, Reason: "broker restarted"})
+			},
+			wantConns: 2,
+		},
+		"ChannelDropped": {
+			drop: func(fb *fakeBroker) {
+				conn := fb.lastConn()
+				conn.mu.Lock()
+				defer conn.mu.Unlock()
+				for _, ch := range conn.channels {
+					_ = ch.Close()
+				}
+			},
+			wantConns: 1,
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			fb := newFakeBroker()
+			b := newTestBus(t, fb)
+
+			// The handler asks for the messages with an odd tag to be
+			// delivered again.
+			handled := make(chan string)
+			h := func(ctx context.Context, msg []byte) {
+				var tag uint64
+				_, _ = fmt.Sscanf(string(msg), "%d", &tag)
+				if tag%2 == 1 {
+					internal.Requeue(ctx)
+				}
+				handled <- string(msg)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- b.Subscribe(ctx, "event.created", h) }()
+
+			deliver := func(deliveries chan amqp.Delivery, tag uint64) {
+				t.Helper()
+				body := fmt.Sprint(tag)
+				deliveries <- amqp.Delivery{Acknowledger: fb, DeliveryTag: tag, Body: []byte(body)}
+				select {
+				case got := <-handled:
+					if got != body {
+						t.Errorf("want message %q to be handled, got %q", body, got)
+					}
+				case <-time.After(waitTimeout):
+					t.Fatalf("want message %q to be handled", body)
+				}
+			}
+
+			deliver(fb.consumer(t), 1)
+			test.drop(fb)
+			deliver(fb.consumer(t), 2)
+
+			cancel()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("want the subscription to end without an error, got %v", err)
+				}
+			case <-time.After(waitTimeout):
+				t.Fatal("want the subscription to end")
+			}
+
+			if _, conns := fb.counts(); conns != test.wantConns {
+				t.Errorf("want %d connections, got %d", test.wantConns, conns)
+			}
+			fb.mu.Lock()
+			defer fb.mu.Unlock()
+			want := map[uint64]string{1: "nack", 2: "ack"}
+			for tag, outcome := range want {
+				if fb.settled[tag] != outcome {
+					t.Errorf("want messages settled as %v, got %v", want, fb.settled)
+					break
+				}
+			}
+		})
+	}
+}
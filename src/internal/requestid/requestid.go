@@ -0,0 +1,63 @@
+package requestid
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	. "github.com/eventscompass/booking-service/src/internal"
+)
+
+// Header is the http header carrying the id of the request.
+const Header = "X-Request-ID"
+
+// maxLen is the maximum length of a request id provided by the client.
+const maxLen = 128
+
+// key is the key under which the request id is stored in the context.
+type key struct{}
+
+// Middleware assigns an id to every request. The id provided by the client, or
+// by a proxy, in the [Header] is kept if it is valid, otherwise a new one is
+// generated. The id is stored in the request context and is echoed back in the
+// response header.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			var err error
+			if id, err = NewID(); err != nil {
+				slog.Warn("failed to generate request id", slog.String("error", err.Error()))
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), key{}, id)))
+	})
+}
+
+// FromContext returns the id of the request, or an empty string if the
+// request has no id.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
+
+// valid returns true if the id is non-empty, not too long, and consists only of
+// letters, digits and the characters '-', '_' and '.'.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+// MaxBodyBytes is the maximum size of the body of a request.
+const MaxBodyBytes = 64 << 10
+
+// ErrTooLarge is returned when the body of a request exceeds [MaxBodyBytes].
+var ErrTooLarge = fmt.Errorf("%w: body too large", service.ErrBadRequest)
+
+// Decode strictly decodes the json body of the request into v. The body must
+// hold exactly one json value of at most [MaxBodyBytes], and must not contain
+// fields that v does not define. This function returns [ErrTooLarge] if the
+// body is too large. This function returns an [*Error] if a field is unknown
+// or has the wrong type. This function returns [service.ErrBadRequest] if the
+// body cannot be decoded otherwise.
+func Decode(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: body must hold a single json value", service.ErrBadRequest)
+	}
+	return nil
+}
+
+// BodyError converts the error returned when reading the body of a request
+// that was limited with [http.MaxBytesReader].
+func BodyError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return fmt.Errorf("%w: limit is %d bytes", ErrTooLarge, maxErr.Limit)
+	}
+	return fmt.Errorf("%w: read body: %v", service.ErrBadRequest, err)
+}
+
+// unknownFieldPrefix is the prefix of the error returned by the json decoder
+// for unknown fields. The decoder does not define a type for that error.
+const unknownFieldPrefix = "json: unknown field "
+
+// decodeError converts the error returned by the json decoder.
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("%w: empty body", service.ErrBadRequest)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return &Error{Violations: []Violation{
+			{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()},
+		}}
+	case strings.HasPrefix(err.Error(), unknownFieldPrefix):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), unknownFieldPrefix), `"`)
+		return &Error{Violations: []Violation{{Field: field, Message: "is not allowed"}}}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("%w: truncated body", service.ErrBadRequest)
+	default:
+		return BodyError(err)
+	}
+}
@@ -0,0 +1,111 @@
+package validation
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+type payload struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func decode(t *testing.T, body string) error {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	var p payload
+	return Decode(httptest.NewRecorder(), r, &p)
+}
+
+func TestDecode(t *testing.T) {
+	tests := map[string]struct {
+		body       string
+		wantErr    error
+		wantFields []string
+	}{
+		"Valid": {body: `{"name":"a","count":1}`},
+		"UnknownField": {
+			body:       `{"name":"a","extra":1}`,
+			wantErr:    ErrInvalid,
+			wantFields: []string{"extra"},
+		},
+		"WrongType": {
+			body:       `{"count":"one"}`,
+			wantErr:    ErrInvalid,
+			wantFields: []string{"count"},
+		},
+		"Empty":        {body: ``, wantErr: service.ErrBadRequest},
+		"Truncated":    {body: `{"name":`, wantErr: service.ErrBadRequest},
+		"TrailingData": {body: `{"name":"a"}{}`, wantErr: service.ErrBadRequest},
+		"TooLarge": {
+			body:    `{"name":"` + strings.Repeat("a", MaxBodyBytes) + `"}`,
+			wantErr: ErrTooLarge,
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			err := decode(t, test.body)
+			if test.wantErr == nil {
+				if err != nil {
+					t.Fatalf("want no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, test.wantErr) || !errors.Is(err, service.ErrBadRequest) {
+				t.Fatalf("want error %v, got %v", test.wantErr, err)
+			}
+			if test.wantFields != nil {
+				checkFields(t, err, test.wantFields)
+			}
+		})
+	}
+}
+
+func TestValidatorReportsEveryViolation(t *testing.T) {
+	var v Validator
+	v.Check(false, "user_id", "is required")
+	if v.Check(true, "event_id", "is required") {
+		v.Check(false, "event_id", "must be at most 64 bytes")
+	}
+	v.Check(true, "date", "must be in the future")
+
+	err := v.Err()
+	if !errors.Is(err, ErrInvalid) || !errors.Is(err, service.ErrBadRequest) {
+		t.Fatalf("want an invalid fields error, got %v", err)
+	}
+	checkFields(t, err, []string{"user_id", "event_id"})
+	want := "user_id is required, event_id must be at most 64 bytes"
+	if !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("want message ending with %q, got %q", want, err.Error())
+	}
+}
+
+func TestValidatorWithoutViolations(t *testing.T) {
+	var v Validator
+	v.Check(true, "user_id", "is required")
+	if err := v.Err(); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+}
+
+func checkFields(t *testing.T, err error, want []string) {
+	t.Helper()
+	var verr *Error
+	if !errors.As(err, &verr) {
+		t.Fatalf("want an *Error, got %T", err)
+	}
+	if len(verr.Violations) != len(want) {
+		t.Fatalf("want violations of %v, got %+v", want, verr.Violations)
+	}
+	for i, field := range want {
+		if verr.Violations[i].Field != field {
+			t.Errorf("want violation %d of %q, got %q", i, field, verr.Violations[i].Field)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/eventscompass/service-framework/service"
+)
+
+// ErrInvalid classifies the errors of requests which are well-formed, but
+// whose fields violate the validation rules. It is always accompanied by
+// [service.ErrBadRequest], so that the error is handled as a bad request by
+// callers that do not know about validation.
+var ErrInvalid = errors.New("invalid fields")
+
+// Violation describes why a field of a request is invalid. Fields are named as
+// in the json encoding of the request.
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is the error of a request with invalid fields. It lists every
+// violation, so that the client can fix all of them at once.
+type Error struct {
+	Violations []Violation
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = fmt.Sprintf("%s %s", v.Field, v.Message)
+	}
+	return fmt.Sprintf("%v: %v: %s", service.ErrBadRequest, ErrInvalid, strings.Join(msgs, ", "))
+}
+
+// Unwrap makes the error match both [ErrInvalid] and [service.ErrBadRequest].
+func (e *Error) Unwrap() []error {
+	return []error{ErrInvalid, service.ErrBadRequest}
+}
+
+// Validator collects the violations of the validation rules of a request.
+// The zero value is ready to use.
+type Validator struct {
+	violations []Violation
+}
+
+// Check records a violation of the given field if ok is false. It returns ok,
+// so that dependent checks can be skipped.
+func (v *Validator) Check(ok bool, field, msg string) bool {
+	if !ok {
+		v.violations = append(v.violations, Violation{Field: field, Message: msg})
+	}
+	return ok
+}
+
+// Err returns an [*Error] listing the recorded violations, or nil if there are
+// none.
+func (v *Validator) Err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &Error{Violations: v.violations}
+}
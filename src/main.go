@@ -2,15 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"sync"
 
 	"github.com/caarlos0/env/v6"
+	"google.golang.org/grpc"
 
 	"github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/auth"
+	"github.com/eventscompass/booking-service/src/internal/booking"
+	"github.com/eventscompass/booking-service/src/internal/consumer"
+	"github.com/eventscompass/booking-service/src/internal/health"
+	"github.com/eventscompass/booking-service/src/internal/memory"
 	"github.com/eventscompass/booking-service/src/internal/mongodb"
+	"github.com/eventscompass/booking-service/src/internal/outbox"
+	"github.com/eventscompass/booking-service/src/internal/rabbitmq"
 	"github.com/eventscompass/service-framework/pubsub"
-	"github.com/eventscompass/service-framework/pubsub/rabbitmq"
 	"github.com/eventscompass/service-framework/service"
 )
 
@@ -23,9 +34,40 @@ type BookingService struct {
 	// http requests to the rest api of the service.
 	restHandler http.Handler
 
+	// grpcServer serves the grpc api of the service.
+	grpcServer *grpc.Server
+
+	// stopping is closed once the servers are stopping, in order to end
+	// the grpc streams. stopOnce guards the closing.
+	stopping chan struct{}
+	stopOnce sync.Once
+
 	// bookingsBus is used for publishing and subscribing to messages.
 	bookingsBus service.MessageBus
 
+	// subscriptions wraps the bookingsBus and tracks the status of
+	// the subscriptions of the service.
+	subscriptions *health.Bus
+
+	// readiness checks whether the dependencies of the service are
+	// healthy, i.e. whether the service is ready to serve requests.
+	readiness *health.Checker
+
+	// verifier authenticates the callers of the apis. It is nil if
+	// the authentication is disabled.
+	verifier *auth.Verifier
+
+	// relay publishes the messages from the outbox to the bookingsBus.
+	relay *outbox.Relay
+
+	// workers tracks the goroutines that run in the background, i.e.
+	// the outbox relay and the hold reaper.
+	workers sync.WaitGroup
+
+	// consumer runs the event handlers, retrying failed messages
+	// and dead-lettering the ones that cannot be handled.
+	consumer *consumer.Consumer
+
 	// events are the messages from the message bus for which the
 	// service is subscribed. With every event is associated an
 	// event handler function,
@@ -34,6 +76,9 @@ type BookingService struct {
 	// bookingsDB is used to read and store bookings in a container database.
 	bookingsDB internal.BookingsContainer
 
+	// bookings implements the business logic for managing bookings.
+	bookings *booking.Manager
+
 	// cfg is used to configure the service.
 	cfg *Config
 }
@@ -48,23 +93,59 @@ func (s *BookingService) Init(ctx context.Context) error {
 	s.cfg = &cfg
 
 	// Init the database layer.
-	mongoCfg := mongodb.Config(s.cfg.BookingsDB)
-	db, err := mongodb.NewMongoDBContainer(ctx, &mongoCfg)
+	db, err := s.initDB(ctx)
 	if err != nil {
 		return fmt.Errorf("init db: %w", err)
 	}
 	s.bookingsDB = db
 
 	// Init the message bus.
-	busCfg := rabbitmq.Config(s.cfg.BookingsMQ)
-	bus, err := rabbitmq.NewAMQPBus(&busCfg, pubsub.EventsExchange)
+	bus, err := s.initBus()
 	if err != nil {
 		return fmt.Errorf("init mq: %w", err)
 	}
-	s.bookingsBus = bus
+	s.subscriptions = health.TrackSubscriptions(bus)
+	s.bookingsBus = s.subscriptions
+
+	// Init the outbox relay and start draining the outbox in the
+	// background. The goroutine stops once the service is shut down.
+	s.relay = outbox.NewRelay(s.bookingsDB, s.bookingsBus, &outbox.Config{
+		Interval:       s.cfg.OutboxRelayInterval,
+		Retention:      s.cfg.OutboxRetention,
+		MaxAttempts:    s.cfg.OutboxMaxAttempts,
+		PublishTimeout: s.cfg.OutboxPublishTimeout,
+	})
+	s.goWorker(func() { s.relay.Run(ctx) })
+
+	// Init the domain layer and start expiring seat holds in the
+	// background.
+	s.bookings = booking.NewManager(s.bookingsDB, s.relay, &booking.Config{
+		HoldTTL: s.cfg.HoldTTL,
+	})
+	s.goWorker(func() { s.bookings.RunReaper(ctx, s.cfg.HoldReaperInterval) })
 
-	// Init the rest API of the service.
-	s.initREST()
+	// Init the consumer of the received messages.
+	s.consumer = consumer.NewConsumer(s.bookingsDB, &consumer.Config{
+		MaxAttempts:    s.cfg.ConsumerMaxAttempts,
+		InitialBackoff: s.cfg.ConsumerInitialBackoff,
+		MaxBackoff:     s.cfg.ConsumerMaxBackoff,
+	})
+
+	// Init the readiness checks of the dependencies.
+	s.initReadiness()
+
+	// Init the authentication of the callers.
+	verifier, err := s.initAuth()
+	if err != nil {
+		return fmt.Errorf("init auth: %w", err)
+	}
+	s.verifier = verifier
+
+	// Init the rest and the grpc APIs of the service.
+	if err := s.initREST(); err != nil {
+		return fmt.Errorf("init rest: %w", err)
+	}
+	s.initGRPC(ctx)
 
 	// Init the events.
 	s.initEvents()
@@ -72,6 +153,185 @@ func (s *BookingService) Init(ctx context.Context) error {
 	return nil
 }
 
+// goWorker runs the given function in a background goroutine, which is waited
+// for on shutdown.
+func (s *BookingService) goWorker(f func()) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		f()
+	}()
+}
+
+// Shutdown releases the resources of the service. It must be called after the
+// servers and the subscriptions have stopped and the context passed to Init is
+// cancelled, i.e. after [service.Start] has returned, since the framework does
+// not call it. The resources are released in dependency order: the in-flight event
+// handlers are drained and the background workers are stopped, then the
+// message bus is closed, and finally the database.
+func (s *BookingService) Shutdown(ctx context.Context) error {
+	if s.cfg == nil {
+		return nil // the service was never initialized
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	// The servers have stopped by now, unless the service was never
+	// started, thus there are no streams left to end.
+	if s.stopping != nil {
+		s.stopGRPC()
+	}
+
+	var errs []error
+	if s.consumer != nil {
+		slog.Info("draining event handlers")
+		if err := s.consumer.Drain(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("consumer: %w", err))
+		}
+	}
+
+	slog.Info("stopping background workers")
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf(
+			"%w: workers: stop: %v", service.ErrUnexpected, ctx.Err()))
+	}
+
+	if s.bookingsBus != nil {
+		slog.Info("closing message bus")
+		if err := s.bookingsBus.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("bus: close: %w", err))
+		}
+	}
+
+	if s.bookingsDB != nil {
+		slog.Info("closing database")
+		if err := s.bookingsDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("db: close: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// initDB initializes the container selected by the configured database driver.
+func (s *BookingService) initDB(ctx context.Context) (internal.BookingsContainer, error) {
+	switch s.cfg.BookingsDBDriver {
+	case "mongodb":
+		mongoCfg := mongodb.Config(s.cfg.BookingsDB)
+		db, err := mongodb.NewMongoDBContainer(ctx, &mongoCfg)
+		if err != nil {
+			return nil, fmt.Errorf("mongodb: %w", err)
+		}
+		return db, nil
+	case "memory":
+		return memory.NewMemoryContainer(), nil
+	default:
+		return nil, fmt.Errorf(
+			"%w: unknown db driver %q", service.ErrUnexpected, s.cfg.BookingsDBDriver)
+	}
+}
+
+// initBus initializes the message bus selected by the configured bus driver.
+func (s *BookingService) initBus() (service.MessageBus, error) {
+	switch s.cfg.BookingsMQDriver {
+	case "rabbitmq":
+		busCfg := rabbitmq.Config(s.cfg.BookingsMQ)
+		bus, err := rabbitmq.NewBus(&busCfg, pubsub.EventsExchange)
+		if err != nil {
+			return nil, fmt.Errorf("rabbitmq: %w", err)
+		}
+		return bus, nil
+	case "memory":
+		return memory.NewBus(), nil
+	default:
+		return nil, fmt.Errorf(
+			"%w: unknown mq driver %q", service.ErrUnexpected, s.cfg.BookingsMQDriver)
+	}
+}
+
+// initReadiness initializes the checks which tell whether the service is ready
+// to serve requests: the database must be reachable, the message bus must be
+// connected, and all subscriptions must be running.
+func (s *BookingService) initReadiness() {
+	s.readiness = health.NewChecker(s.cfg.HealthCheckTimeout)
+	s.readiness.Add("database", s.bookingsDB.Ping)
+	s.readiness.Add("bus", s.subscriptions.CheckConnection)
+	s.readiness.Add("subscriptions", s.subscriptions.CheckSubscriptions)
+}
+
+// initAuth initializes the verifier of the bearer tokens of the callers, with
+// the keys from the config. It returns nil if the authentication is disabled.
+// This function returns [service.ErrUnexpected] if no key is configured or a
+// key cannot be loaded.
+func (s *BookingService) initAuth() (*auth.Verifier, error) {
+	cfg := s.cfg.Auth
+	if !cfg.Enabled {
+		slog.Warn("authentication is disabled")
+		return nil, nil //nolint:nilnil // a nil verifier disables the authentication
+	}
+
+	var keys []*auth.Key
+	if cfg.HMACSecret != "" {
+		key, err := auth.NewHMACKey("", []byte(cfg.HMACSecret))
+		if err != nil {
+			return nil, fmt.Errorf("hmac key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if cfg.RSAPublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.RSAPublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: read rsa public key: %v", service.ErrUnexpected, err)
+		}
+		key, err := auth.ParseRSAPublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("rsa public key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if cfg.JWKSFile != "" {
+		data, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: read jwks: %v", service.ErrUnexpected, err)
+		}
+		set, err := auth.ParseJWKS(data)
+		if err != nil {
+			return nil, fmt.Errorf("jwks: %w", err)
+		}
+		keys = append(keys, set...)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: authentication is enabled, but no key is configured",
+			service.ErrUnexpected)
+	}
+
+	return auth.NewVerifier(keys, &auth.Config{
+		Issuer:    cfg.Issuer,
+		Audience:  cfg.Audience,
+		AdminRole: cfg.AdminRole,
+		Leeway:    cfg.Leeway,
+	}), nil
+}
+
 func main() {
-	service.Start(&BookingService{})
+	s := &BookingService{}
+
+	// Start blocks until the servers and the subscriptions have stopped, but
+	// it does not release the resources of the service: the [service.CloudService]
+	// interface of the framework has no shutdown hook, which Start could call.
+	// Thus the service is shut down here, once Start has returned.
+	service.Start(s)
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		slog.Error("failed to shut down service", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	slog.Info("service stopped")
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eventscompass/booking-service/src/internal/consumer"
+	"github.com/eventscompass/booking-service/src/internal/memory"
+	"github.com/eventscompass/service-framework/service"
+)
+
+// steps records the steps of the shutdown, i.e. the messages logged by the
+// service and the events of the fakes, in order.
+type steps struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (s *steps) record(step string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = append(s.list, step)
+}
+
+func (s *steps) get() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.list)
+}
+
+// Enabled implements the [slog.Handler] interface.
+func (*steps) Enabled(context.Context, slog.Level) bool { return true }
+
+// Handle implements the [slog.Handler] interface.
+func (s *steps) Handle(_ context.Context, r slog.Record) error {
+	s.record(r.Message)
+	return nil
+}
+
+// WithAttrs implements the [slog.Handler] interface.
+func (s *steps) WithAttrs([]slog.Attr) slog.Handler { return s }
+
+// WithGroup implements the [slog.Handler] interface.
+func (s *steps) WithGroup(string) slog.Handler { return s }
+
+// closingBus is a message bus which records when it is closed, and fails to
+// publish afterwards.
+type closingBus struct {
+	*memory.Bus
+	steps *steps
+}
+
+func (b closingBus) Close() error {
+	b.steps.record("bus closed")
+	return b.Bus.Close() //nolint:wrapcheck // passed through
+}
+
+// closingContainer is a container which records when it is closed, and is not
+// reachable afterwards.
+type closingContainer struct {
+	*memory.MemoryContainer
+	steps *steps
+
+	// mu guards closed.
+	mu     sync.Mutex
+	closed bool
+}
+
+func (c *closingContainer) Ping(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return fmt.Errorf("%w: container is closed", service.ErrConnectionClosed)
+	}
+	return nil
+}
+
+func (c *closingContainer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.steps.record("db closed")
+	return nil
+}
+
+func TestShutdownOrder(t *testing.T) {
+	// The steps are recorded through the default logger.
+	var got steps
+	logger := slog.Default()
+	slog.SetDefault(slog.New(&got))
+	t.Cleanup(func() { slog.SetDefault(logger) })
+
+	bus := closingBus{Bus: memory.NewBus(), steps: &got}
+	db := &closingContainer{MemoryContainer: memory.NewMemoryContainer(), steps: &got}
+	s := &BookingService{
+		bookingsBus: bus,
+		bookingsDB:  db,
+		consumer:    consumer.NewConsumer(db, &consumer.Config{MaxAttempts: 1}),
+		cfg:         &Config{ShutdownTimeout: time.Second},
+	}
+
+	// A message is being handled, and a worker is running, when the
+	// service is shut down. Both still use the bus and the database. The
+	// handler finishes a while after the shutdown has started, and the
+	// worker a while after the handler.
+	use := func(who string) {
+		if err := bus.Publish(context.Background(), "booking.created", nil); err != nil {
+			t.Errorf("%s: want the bus to be open, got %v", who, err)
+		}
+		if err := db.Ping(context.Background()); err != nil {
+			t.Errorf("%s: want the database to be open, got %v", who, err)
+		}
+	}
+	started, handled := make(chan struct{}), make(chan struct{})
+	h := s.consumer.Handle("event.created", func(context.Context, []byte) error {
+		close(started)
+		time.Sleep(20 * time.Millisecond)
+		use("handler")
+		got.record("handler done")
+		close(handled)
+		return nil
+	})
+	go h(context.Background(), []byte(`{}`))
+	s.goWorker(func() {
+		<-handled
+		time.Sleep(20 * time.Millisecond)
+		use("worker")
+		got.record("worker done")
+	})
+	<-started
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	want := []string{
+		"draining event handlers",
+		"handler done",
+		"stopping background workers",
+		"worker done",
+		"closing message bus",
+		"bus closed",
+		"closing database",
+		"db closed",
+	}
+	var order []string
+	for _, step := range got.get() {
+		if slices.Contains(want, step) {
+			order = append(order, step)
+		}
+	}
+	if !slices.Equal(order, want) {
+		t.Errorf("want the steps\n%q\ngot\n%q", want, order)
+	}
+}
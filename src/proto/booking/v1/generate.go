@@ -0,0 +1,5 @@
+package bookingv1
+
+// The code of this package is generated from booking.proto. After changing the
+// proto file, regenerate the code from the src/proto directory.
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative booking/v1/booking.proto
@@ -1,14 +1,28 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi"
 
 	"github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/auth"
+	"github.com/eventscompass/booking-service/src/internal/booking"
+	"github.com/eventscompass/booking-service/src/internal/consumer"
+	"github.com/eventscompass/booking-service/src/internal/health"
+	"github.com/eventscompass/booking-service/src/internal/idempotency"
+	"github.com/eventscompass/booking-service/src/internal/openapi"
+	"github.com/eventscompass/booking-service/src/internal/problem"
+	"github.com/eventscompass/booking-service/src/internal/requestid"
+	"github.com/eventscompass/booking-service/src/internal/validation"
+	"github.com/eventscompass/service-framework/service"
 )
 
 // REST implements the [service.CloudService] interface.
@@ -18,30 +32,143 @@ func (s *BookingService) REST() http.Handler {
 
 // initREST initializes the handler for the rest server part of the service.
 // This function creates a router and registers with that router the handlers
-// for the http endpoints.
-func (s *BookingService) initREST() {
+// for the http endpoints. The routes are checked against the OpenAPI document
+// of the service, so that the service does not start if they disagree.
+func (s *BookingService) initREST() error {
+	spec, err := openapi.Load()
+	if err != nil {
+		return fmt.Errorf("load openapi: %w", err)
+	}
+
 	restHandler := &restHandler{
-		bookingsDB: s.bookingsDB,
+		bookings: s.bookings,
+		consumer: s.consumer,
 	}
+	keys := idempotency.NewKeys(s.bookingsDB, s.cfg.IdempotencyKeyTTL)
 	mux := chi.NewMux()
 
-	// API routes.
-	mux.Post("/api/bookings", restHandler.create)
-	mux.Get("/api/bookings/{id}", restHandler.read)
+	// Every request gets an id, which is included in the error responses.
+	// Errors are reported as problem details, including the errors of the
+	// router itself.
+	mux.Use(requestid.Middleware)
+	mux.NotFound(problem.NotFound)
+	mux.MethodNotAllowed(problem.MethodNotAllowed)
+
+	// The requests and the responses are validated against the OpenAPI
+	// document if enabled, which is meant for tests.
+	if s.cfg.OpenAPIValidation {
+		mux.Use(spec.Middleware)
+	}
+
+	// API routes. The callers are authenticated if enabled, and only
+	// admins may use the admin routes. Without authentication the admin
+	// routes are closed, unless they are explicitly opened to anonymous
+	// callers.
+	mux.Group(func(mux chi.Router) {
+		if s.verifier != nil {
+			mux.Use(authenticate(s.verifier))
+		}
+		admin := mux.With(requireAdmin)
+		if s.verifier == nil && s.cfg.Auth.AnonymousAdmin {
+			slog.Warn("admin api is open to anonymous callers")
+			admin = mux.With(anonymousAdmin)
+		}
+
+		mux.With(keys.Middleware).Post("/api/bookings", restHandler.create)
+		mux.Get("/api/bookings", restHandler.list)
+		mux.Get("/api/bookings/{id}", restHandler.read)
+		mux.Post("/api/bookings/{id}/cancel", restHandler.cancel)
+		mux.Post("/api/holds", restHandler.hold)
+		mux.Post("/api/holds/{id}/confirm", restHandler.confirm)
+		mux.Post("/api/waitlist", restHandler.joinWaitlist)
+		mux.Get("/api/waitlist/{id}", restHandler.waitlistPosition)
+		mux.Delete("/api/waitlist/{id}", restHandler.leaveWaitlist)
+		mux.Get("/api/events/{id}", restHandler.readEvent)
+		mux.Get("/api/locations/{id}", restHandler.readLocation)
 
-	// Health check.
-	mux.Handle("/healthz", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Admin routes.
+		admin.Put("/api/admin/events/{id}/capacity", restHandler.setEventCapacity)
+		admin.Put("/api/admin/locations/{id}/capacity", restHandler.setLocationCapacity)
+		admin.Get("/api/admin/deadletters", restHandler.listDeadLetters)
+		admin.Post("/api/admin/deadletters/{id}/replay", restHandler.replayDeadLetter)
+	})
+
+	// Health checks. The liveness check only tells that the process is
+	// serving requests, while the readiness check tells whether the
+	// dependencies of the service are healthy.
+	mux.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w, "I am healthy and strong, buddy!")
-	}))
+	})
+	mux.Get("/livez", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf8")
+		if err := json.NewEncoder(w).Encode(&health.Report{Status: health.StatusUp}); err != nil {
+			slog.Info("failed to write response", slog.String("error", err.Error()))
+		}
+	})
+	mux.Method(http.MethodGet, "/readyz", s.readiness)
+
+	// The OpenAPI document describing the routes above.
+	mux.Method(http.MethodGet, "/openapi.json", spec)
 
+	if err := spec.CheckRoutes(mux); err != nil {
+		return fmt.Errorf("check routes: %w", err)
+	}
 	s.restHandler = mux
+	return nil
+}
+
+// authenticate returns a middleware which authenticates the callers with the
+// bearer tokens of their requests. The principal of the caller is stored in
+// the request context, while requests without a valid token are rejected.
+func authenticate(v *auth.Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			token, err := auth.BearerToken(r.Header.Get("Authorization"))
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				problem.Write(ctx, w, err)
+				return
+			}
+			p, err := v.Verify(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				problem.Write(ctx, w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(ctx, p)))
+		})
+	}
+}
+
+// anonymousAdmin is a middleware which makes the anonymous callers admins. It
+// is used only while the authentication is disabled.
+func anonymousAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := auth.WithPrincipal(r.Context(), &auth.Principal{Admin: true})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireAdmin is a middleware which rejects the requests of callers that are
+// not admins.
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := auth.RequireAdmin(r.Context()); err != nil {
+			problem.Write(r.Context(), w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 // restHandler handles http requests. It is the bridge between the rest api and
 // the business logic. Every rest endpoint exposed by the server will be served
 // by calling one of the handler methods.
 type restHandler struct {
-	bookingsDB internal.BookingsContainer
+	bookings *booking.Manager
+	consumer *consumer.Consumer
 }
 
 func (h *restHandler) create(w http.ResponseWriter, r *http.Request) {
@@ -49,23 +176,26 @@ func (h *restHandler) create(w http.ResponseWriter, r *http.Request) {
 
 	// Decode the request body.
 	var booking internal.Booking
-	if err := json.NewDecoder(r.Body).Decode(&booking); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := validation.Decode(w, r, &booking); err != nil {
+		problem.Write(ctx, w, fmt.Errorf("decode booking: %w", err))
 		return
 	}
 
 	// Create the booking.
 	slog.Info("request to create booking", slog.Any("booking", booking))
-	err := h.bookingsDB.Create(ctx, internal.BookingsCollection, booking)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.bookings.Create(ctx, &booking); err != nil {
+		problem.Write(ctx, w, err)
 		return
 	}
 	slog.Info("booking successfully created")
 
 	// Write the response.
 	w.Header().Set("Location", fmt.Sprintf("%s/%s", r.URL.Path, booking.ID))
+	w.Header().Set("Content-Type", "application/json; charset=utf8")
 	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&booking); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
 }
 
 func (h *restHandler) read(w http.ResponseWriter, r *http.Request) {
@@ -76,15 +206,344 @@ func (h *restHandler) read(w http.ResponseWriter, r *http.Request) {
 
 	// Get the booking.
 	slog.Info("request to read booking", slog.String("id", id))
-	booking, err := h.bookingsDB.GetByID(ctx, internal.BookingsCollection, id)
+	booking, err := h.bookings.Get(ctx, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Write(ctx, w, err)
 		return
 	}
 
 	// Write the response.
 	w.Header().Set("Content-Type", "application/json; charset=utf8")
-	if err := json.NewEncoder(w).Encode(&booking); err != nil {
-		slog.Info("failed to write response", err)
+	if err := json.NewEncoder(w).Encode(booking); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+func (h *restHandler) list(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Decode the request query.
+	query, err := listQuery(r.URL.Query())
+	if err != nil {
+		problem.Write(ctx, w, err)
+		return
+	}
+
+	// List the bookings.
+	slog.Info("request to list bookings", slog.Any("query", query))
+	page, err := h.bookings.List(ctx, query)
+	if err != nil {
+		problem.Write(ctx, w, err)
+		return
+	}
+
+	// Write the response.
+	w.Header().Set("Content-Type", "application/json; charset=utf8")
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+// listQuery decodes the url query parameters of a request to list bookings.
+// This function returns [service.ErrBadRequest] if a parameter is malformed.
+func listQuery(values url.Values) (*booking.ListQuery, error) {
+	q := &booking.ListQuery{
+		UserID:  values.Get("user_id"),
+		EventID: values.Get("event_id"),
+		Status:  internal.BookingStatus(values.Get("status")),
+		Cursor:  values.Get("cursor"),
+	}
+
+	var err error
+	if v := values.Get("from"); v != "" {
+		if q.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, fmt.Errorf("%w: parse from: %v", service.ErrBadRequest, err)
+		}
+	}
+	if v := values.Get("to"); v != "" {
+		if q.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, fmt.Errorf("%w: parse to: %v", service.ErrBadRequest, err)
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("%w: parse limit: %v", service.ErrBadRequest, err)
+		}
+	}
+	return q, nil
+}
+
+func (h *restHandler) cancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Decode the request key.
+	id := chi.URLParam(r, "id")
+
+	// Cancel the booking.
+	slog.Info("request to cancel booking", slog.String("id", id))
+	if err := h.bookings.Cancel(ctx, id); err != nil {
+		problem.Write(ctx, w, err)
+		return
 	}
+	slog.Info("booking successfully cancelled")
+
+	// Write the response.
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *restHandler) hold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Decode the request body.
+	var hold internal.Booking
+	if err := validation.Decode(w, r, &hold); err != nil {
+		problem.Write(ctx, w, fmt.Errorf("decode hold: %w", err))
+		return
+	}
+
+	// Create the hold.
+	slog.Info("request to hold a seat", slog.Any("hold", hold))
+	if err := h.bookings.Hold(ctx, &hold); err != nil {
+		problem.Write(ctx, w, err)
+		return
+	}
+	slog.Info("hold successfully created")
+
+	// Write the response. The hold is a pending booking.
+	w.Header().Set("Location", fmt.Sprintf("/api/bookings/%s", hold.ID))
+	w.Header().Set("Content-Type", "application/json; charset=utf8")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&hold); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+func (h *restHandler) confirm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Decode the request key.
+	id := chi.URLParam(r, "id")
+
+	// Confirm the hold.
+	slog.Info("request to confirm hold", slog.String("id", id))
+	if err := h.bookings.Confirm(ctx, id); err != nil {
+		problem.Write(ctx, w, err)
+		return
+	}
+	slog.Info("hold successfully confirmed")
+
+	// Write the response.
+	w.Header().Set("Location", fmt.Sprintf("/api/bookings/%s", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// waitlistResponse is the response body describing a waitlist entry.
+type waitlistResponse struct {
+	internal.WaitlistEntry
+	Position int `json:"position"`
+}
+
+func (h *restHandler) joinWaitlist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Decode the request body.
+	var entry internal.WaitlistEntry
+	if err := validation.Decode(w, r, &entry); err != nil {
+		problem.Write(ctx, w, fmt.Errorf("decode entry: %w", err))
+		return
+	}
+
+	// Join the waitlist.
+	slog.Info("request to join waitlist", slog.Any("entry", entry))
+	position, err := h.bookings.JoinWaitlist(ctx, &entry)
+	if err != nil {
+		problem.Write(ctx, w, err)
+		return
+	}
+	slog.Info("waitlist successfully joined", slog.Int("position", position))
+
+	// Write the response.
+	w.Header().Set("Location", fmt.Sprintf("%s/%s", r.URL.Path, entry.ID))
+	w.Header().Set("Content-Type", "application/json; charset=utf8")
+	w.WriteHeader(http.StatusCreated)
+	resp := waitlistResponse{WaitlistEntry: entry, Position: position}
+	if err := json.NewEncoder(w).Encode(&resp); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+func (h *restHandler) waitlistPosition(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Decode the request key.
+	id := chi.URLParam(r, "id")
+
+	// Get the waitlist entry.
+	slog.Info("request to read waitlist entry", slog.String("id", id))
+	entry, position, err := h.bookings.WaitlistPosition(ctx, id)
+	if err != nil {
+		problem.Write(ctx, w, err)
+		return
+	}
+
+	// Write the response.
+	w.Header().Set("Content-Type", "application/json; charset=utf8")
+	resp := waitlistResponse{WaitlistEntry: *entry, Position: position}
+	if err := json.NewEncoder(w).Encode(&resp); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+func (h *restHandler) leaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Decode the request key.
+	id := chi.URLParam(r, "id")
+
+	// Leave the waitlist.
+	slog.Info("request to leave waitlist", slog.String("id", id))
+	if err := h.bookings.LeaveWaitlist(ctx, id); err != nil {
+		problem.Write(ctx, w, err)
+		return
+	}
+	slog.Info("waitlist successfully left")
+
+	// Write the response.
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *restHandler) readEvent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Decode the request key.
+	id := chi.URLParam(r, "id")
+
+	// Get the event.
+	slog.Info("request to read event", slog.String("id", id))
+	event, err := h.bookings.GetEvent(ctx, id)
+	if err != nil {
+		problem.Write(ctx, w, err)
+		return
+	}
+
+	// Write the response.
+	w.Header().Set("Content-Type", "application/json; charset=utf8")
+	if err := json.NewEncoder(w).Encode(event); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+func (h *restHandler) readLocation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Decode the request key.
+	id := chi.URLParam(r, "id")
+
+	// Get the location.
+	slog.Info("request to read location", slog.String("id", id))
+	location, err := h.bookings.GetLocation(ctx, id)
+	if err != nil {
+		problem.Write(ctx, w, err)
+		return
+	}
+
+	// Write the response.
+	w.Header().Set("Content-Type", "application/json; charset=utf8")
+	if err := json.NewEncoder(w).Encode(location); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+// capacityRequest is the request body for setting a capacity.
+type capacityRequest struct {
+	Capacity int `json:"capacity"`
+}
+
+func (h *restHandler) setEventCapacity(w http.ResponseWriter, r *http.Request) {
+	h.setCapacity(w, r, h.bookings.SetEventCapacity)
+}
+
+func (h *restHandler) setLocationCapacity(w http.ResponseWriter, r *http.Request) {
+	h.setCapacity(w, r, h.bookings.SetLocationCapacity)
+}
+
+func (h *restHandler) setCapacity(
+	w http.ResponseWriter,
+	r *http.Request,
+	set func(_ context.Context, id string, capacity int) error,
+) {
+	ctx := r.Context()
+
+	// Decode the request key and body.
+	id := chi.URLParam(r, "id")
+	var req capacityRequest
+	if err := validation.Decode(w, r, &req); err != nil {
+		problem.Write(ctx, w, fmt.Errorf("decode capacity: %w", err))
+		return
+	}
+
+	// Set the capacity.
+	slog.Info(
+		"request to set capacity",
+		slog.String("id", id),
+		slog.Int("capacity", req.Capacity),
+	)
+	if err := set(ctx, id, req.Capacity); err != nil {
+		problem.Write(ctx, w, err)
+		return
+	}
+
+	// Write the response.
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maxDeadLetters is the maximum number of dead letters that are listed.
+const maxDeadLetters = 100
+
+func (h *restHandler) listDeadLetters(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Decode the request query.
+	limit := maxDeadLetters
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeadLetters {
+			problem.Write(ctx, w, fmt.Errorf(
+				"%w: limit must be between 1 and %d", service.ErrBadRequest, maxDeadLetters))
+			return
+		}
+		limit = n
+	}
+
+	// List the dead letters.
+	slog.Info("request to list dead letters", slog.Int("limit", limit))
+	msgs, err := h.consumer.DeadLetters(ctx, limit)
+	if err != nil {
+		problem.Write(ctx, w, err)
+		return
+	}
+
+	// Write the response.
+	w.Header().Set("Content-Type", "application/json; charset=utf8")
+	if err := json.NewEncoder(w).Encode(msgs); err != nil {
+		slog.Info("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+func (h *restHandler) replayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Decode the request key.
+	id := chi.URLParam(r, "id")
+
+	// Replay the dead letter.
+	slog.Info("request to replay dead letter", slog.String("id", id))
+	if err := h.consumer.Replay(ctx, id); err != nil {
+		problem.Write(ctx, w, err)
+		return
+	}
+	slog.Info("dead letter successfully replayed")
+
+	// Write the response.
+	w.WriteHeader(http.StatusNoContent)
 }
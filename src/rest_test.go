@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eventscompass/booking-service/src/internal"
+	"github.com/eventscompass/booking-service/src/internal/problem"
+	"github.com/eventscompass/service-framework/pubsub"
+)
+
+// newTestService initializes the service with the in-memory database and
+// message bus, and with the requests and the responses of the rest api
+// validated against the OpenAPI document. The admin api is open to anonymous
+// callers, unless the given environment variables say otherwise. The database
+// holds the users "u1" and "u2", and the event "e1" with a single seat, which
+// starts in an hour. The service is shut down on cleanup.
+func newTestService(t *testing.T, env map[string]string) *BookingService {
+	t.Helper()
+	t.Setenv("DB_DRIVER", "memory")
+	t.Setenv("MQ_DRIVER", "memory")
+	t.Setenv("OPENAPI_VALIDATION", "true")
+	t.Setenv("AUTH_ANONYMOUS_ADMIN", "true")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := new(BookingService)
+	if err := s.Init(ctx); err != nil {
+		cancel()
+		t.Fatalf("init service: %v", err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("shutdown service: %v", err)
+		}
+	})
+
+	start := time.Now().Add(time.Hour).UTC()
+	entries := []struct {
+		collection string
+		data       any
+	}{
+		{internal.UsersCollection, internal.User{ID: "u1", Name: "Alice"}},
+		{internal.UsersCollection, internal.User{ID: "u2", Name: "Bob"}},
+		{internal.EventsCollection, internal.Event{
+			ID: "e1", Name: "Concert", Start: start, End: start.Add(time.Hour), Capacity: 1,
+		}},
+	}
+	for _, e := range entries {
+		if err := s.bookingsDB.Create(ctx, e.collection, e.data); err != nil {
+			t.Fatalf("create in %q: %v", e.collection, err)
+		}
+	}
+	return s
+}
+
+// newTestServer starts the rest server of a service from [newTestService]. The
+// database is returned along with the server.
+func newTestServer(
+	t *testing.T,
+	env map[string]string,
+) (*httptest.Server, internal.BookingsContainer) {
+	t.Helper()
+	s := newTestService(t, env)
+	srv := httptest.NewServer(s.restHandler)
+	t.Cleanup(srv.Close)
+	return srv, s.bookingsDB
+}
+
+// do sends a request to the server and decodes the json response into out, if
+// not nil. It returns the status code of the response.
+func do(t *testing.T, srv *httptest.Server, method, path, body string, out any) int {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, srv.URL+path, r)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+
+	// The validation middleware turns responses which disagree with the
+	// document into server errors with the "unexpected" code.
+	if resp.StatusCode == http.StatusInternalServerError {
+		t.Fatalf("%s %s: response disagrees with the document: %s", method, path, data)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s: decode response %q: %v", method, path, data, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestContract(t *testing.T) {
+	srv, _ := newTestServer(t, nil)
+
+	var b internal.Booking
+	status := do(t, srv, http.MethodPost, "/api/bookings", `{"user_id":"u1","event_id":"e1"}`, &b)
+	if status != http.StatusCreated || b.ID == "" {
+		t.Fatalf("create booking: want status 201 with an id, got %d and %+v", status, b)
+	}
+
+	var entry struct {
+		ID       string `json:"id"`
+		Position int    `json:"position"`
+	}
+	status = do(t, srv, http.MethodPost, "/api/waitlist", `{"user_id":"u2","event_id":"e1"}`, &entry)
+	if status != http.StatusCreated || entry.Position != 1 {
+		t.Fatalf("join waitlist: want status 201 at position 1, got %d and %+v", status, entry)
+	}
+
+	tests := []struct {
+		method, path, body string
+		wantStatus         int
+		wantCode           string
+	}{
+		{http.MethodGet, "/api/bookings/" + b.ID, "", http.StatusOK, ""},
+		{http.MethodGet, "/api/bookings?user_id=u1&limit=10", "", http.StatusOK, ""},
+		{http.MethodGet, "/api/bookings/missing", "", http.StatusNotFound, "not_found"},
+		{http.MethodGet, "/api/waitlist/" + entry.ID, "", http.StatusOK, ""},
+		{http.MethodGet, "/api/events/e1", "", http.StatusOK, ""},
+		{http.MethodGet, "/api/admin/deadletters", "", http.StatusOK, ""},
+		{http.MethodGet, "/healthz", "", http.StatusOK, ""},
+		{http.MethodGet, "/livez", "", http.StatusOK, ""},
+		{http.MethodGet, "/readyz", "", http.StatusServiceUnavailable, ""},
+		{http.MethodGet, "/openapi.json", "", http.StatusOK, ""},
+		{
+			http.MethodPost, "/api/bookings", `{"user_id":"u1","event_id":"e1","seat":1}`,
+			http.StatusUnprocessableEntity, "invalid_fields",
+		},
+		{
+			http.MethodPost, "/api/bookings", `{"user_id":"","event_id":"e1"}`,
+			http.StatusUnprocessableEntity, "invalid_fields",
+		},
+		{
+			http.MethodPut, "/api/admin/events/e1/capacity", `{"capacity":-1}`,
+			http.StatusUnprocessableEntity, "invalid_fields",
+		},
+		{
+			http.MethodGet, "/api/bookings?limit=many", "",
+			http.StatusUnprocessableEntity, "invalid_fields",
+		},
+		{http.MethodDelete, "/api/waitlist/" + entry.ID, "", http.StatusNoContent, ""},
+		{http.MethodPut, "/api/admin/events/e1/capacity", `{"capacity":2}`, http.StatusNoContent, ""},
+		{http.MethodPost, "/api/bookings/" + b.ID + "/cancel", "", http.StatusNoContent, ""},
+	}
+	for _, test := range tests {
+		var d problem.Details
+		var out any
+		if test.wantCode != "" {
+			out = &d
+		}
+		status := do(t, srv, test.method, test.path, test.body, out)
+		if status != test.wantStatus {
+			t.Errorf("%s %s: want status %d, got %d", test.method, test.path, test.wantStatus, status)
+		}
+		if d.Code != test.wantCode {
+			t.Errorf("%s %s: want code %q, got %q", test.method, test.path, test.wantCode, d.Code)
+		}
+	}
+}
+
+func TestAdminWithoutAuthentication(t *testing.T) {
+	srv, _ := newTestServer(t, map[string]string{"AUTH_ANONYMOUS_ADMIN": "false"})
+
+	var d problem.Details
+	status := do(t, srv, http.MethodGet, "/api/admin/deadletters", "", &d)
+	if status != http.StatusUnauthorized || d.Code != "unauthenticated" {
+		t.Errorf("want status 401 with code %q, got %d and %q", "unauthenticated", status, d.Code)
+	}
+}
+
+func TestReplayDeadLetter(t *testing.T) {
+	srv, db := newTestServer(t, nil)
+
+	// The message could not be handled before, e.g. because the database
+	// was down.
+	d := internal.DeadLetter{
+		ID:        "d1",
+		Topic:     pubsub.EventCreatedTopic,
+		Payload:   []byte(`{"id":"e2","name":"Festival"}`),
+		Attempts:  5,
+		LastError: "database is down",
+		FailedAt:  time.Now().UTC(),
+	}
+	if err := db.Create(context.Background(), internal.DeadLettersCollection, d); err != nil {
+		t.Fatalf("create dead letter: %v", err)
+	}
+
+	var letters []internal.DeadLetter
+	do(t, srv, http.MethodGet, "/api/admin/deadletters", "", &letters)
+	if len(letters) != 1 || letters[0].ID != "d1" {
+		t.Fatalf("want dead letters [d1], got %+v", letters)
+	}
+
+	status := do(t, srv, http.MethodPost, "/api/admin/deadletters/d1/replay", "", nil)
+	if status != http.StatusNoContent {
+		t.Fatalf("replay: want status 204, got %d", status)
+	}
+	if status := do(t, srv, http.MethodGet, "/api/events/e2", "", nil); status != http.StatusOK {
+		t.Errorf("want the replayed event to be stored, got status %d", status)
+	}
+	do(t, srv, http.MethodGet, "/api/admin/deadletters", "", &letters)
+	if len(letters) != 0 {
+		t.Errorf("want the dead letter removed, got %+v", letters)
+	}
+
+	var p problem.Details
+	status = do(t, srv, http.MethodPost, "/api/admin/deadletters/d1/replay", "", &p)
+	if status != http.StatusNotFound || p.Code != "not_found" {
+		t.Errorf("replay again: want status 404, got %d and %q", status, p.Code)
+	}
+}